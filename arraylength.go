@@ -0,0 +1,128 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// checkArrayLengths reports an error when a JSON array would be decoded into a
+// fixed-size Go array of a different length, which encoding/json silently allows
+func checkArrayLengths(data []byte, v interface{}) error {
+	value, err := materialize(data, options{})
+	if err != nil {
+		return err
+	}
+	return checkArrayLength(value, reflect.TypeOf(v), "")
+}
+
+// checkArrayLength walks a materialized value along the destination type t
+func checkArrayLength(value interface{}, t reflect.Type, path string) error {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types with their own decoding define their own length rules
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		if t.Kind() == reflect.Array && len(arr) != t.Len() {
+			return newInvalidJSONError(position{}, fmt.Sprintf("JSON array %shas %d elements, but %s holds %d",
+				pathDescription(path), len(arr), t, t.Len()))
+		}
+		for i, elem := range arr {
+			if err := checkArrayLength(elem, t.Elem(), joinPath(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			if err := checkArrayLength(obj[key], t.Elem(), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			field, ok := findField(t, key)
+			if !ok {
+				continue
+			}
+			if err := checkArrayLength(obj[key], field.Type, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findField finds the struct field encoding/json decodes key into, preferring
+// an exact name match over a case-insensitive one
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold reflect.StructField
+	folded := false
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			continue // promoted fields are visited on their own
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		if name == key {
+			return field, true
+		}
+		if !folded && strings.EqualFold(name, key) {
+			fold, folded = field, true
+		}
+	}
+	return fold, folded
+}
+
+// joinPath appends a key or index to a dotted path
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// pathDescription describes a dotted path for error messages
+func pathDescription(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "at " + path + " "
+}
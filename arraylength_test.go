@@ -0,0 +1,89 @@
+package jsonex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithStrictArrayLength(t *testing.T) {
+	twenty := `[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20]`
+
+	var fixed [10]int
+	if err := Unmarshal([]byte(twenty), &fixed); err != nil {
+		t.Fatalf("Unmarshal without option failed: %v", err)
+	}
+	if fixed[9] != 10 {
+		t.Errorf("Unmarshal without option = %v, expected the first 10 elements", fixed)
+	}
+
+	err := Unmarshal([]byte(twenty), &fixed, WithStrictArrayLength(true))
+	jsonErr, ok := err.(*Error)
+	if !ok || jsonErr.Type != ErrInvalidJSON || !strings.Contains(jsonErr.Message, "has 20 elements") {
+		t.Errorf("Unmarshal with option = %v, expected array length error", err)
+	}
+
+	var short [30]int
+	if err := Unmarshal([]byte("log: "+twenty), &short, WithStrictArrayLength(true)); err == nil {
+		t.Error("Expected error for shorter JSON array")
+	}
+
+	var exact [20]int
+	if err := Unmarshal([]byte(twenty), &exact, WithStrictArrayLength(true)); err != nil {
+		t.Errorf("Unmarshal with matching length failed: %v", err)
+	}
+}
+
+func TestWithStrictArrayLength_Nested(t *testing.T) {
+	type point struct {
+		Coords [2]float64 `json:"coords"`
+	}
+	type shape struct {
+		Points []point
+		Named  map[string][3]int
+	}
+
+	valid := `{"points": [{"coords": [1, 2]}], "named": {"a": [1, 2, 3]}}`
+	var result shape
+	if err := Unmarshal([]byte(valid), &result, WithStrictArrayLength(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	tests := []struct {
+		data string
+		path string
+	}{
+		{`{"points": [{"coords": [1, 2]}, {"coords": [1, 2, 3]}]}`, "points.1.coords"},
+		{`{"named": {"a": [1, 2, 3], "b": [1]}}`, "named.b"},
+	}
+	for _, test := range tests {
+		var result shape
+		err := New(strings.NewReader(test.data), WithStrictArrayLength(true)).Decode(&result)
+		if err == nil || !strings.Contains(err.Error(), "at "+test.path+" ") {
+			t.Errorf("Decode(%s) = %v, expected error at %s", test.data, err, test.path)
+		}
+	}
+}
+
+func TestWithStrictArrayLength_MaxDepth(t *testing.T) {
+	// The length check walks the value, so the nesting depth limit applies before it,
+	// rather than only the depth limit of encoding/json on the fast path
+	data := []byte(strings.Repeat("[", 2000) + strings.Repeat("]", 2000))
+	var result interface{}
+	if err := Unmarshal(data, &result, WithStrictArrayLength(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	depth := 0
+	for value, ok := result.([]interface{}); ok; value, ok = value[0].([]interface{}) {
+		depth++
+		if len(value) == 0 {
+			break
+		}
+	}
+	if depth > 1000 {
+		t.Errorf("Unmarshal extracted %d nested arrays, expected at most 1000", depth)
+	}
+
+	if err := Unmarshal(data, &result, WithStrictArrayLength(true), WithMaxDepth(100)); err == nil {
+		t.Error("Unmarshal beyond WithMaxDepth succeeded, expected error")
+	}
+}
@@ -1,7 +1,12 @@
 package jsonex
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
 	"strings"
 	"testing"
 )
@@ -170,6 +175,7 @@ func BenchmarkJsonex_Decoder_Small(b *testing.B) {
 		if err := decoder.Decode(&result); err != nil {
 			b.Fatal(err)
 		}
+		decoder.Close()
 	}
 }
 
@@ -196,6 +202,39 @@ func BenchmarkJsonex_Decoder_Robust(b *testing.B) {
 	}
 }
 
+// String input benchmarks: the string is copied once, as with Unmarshal([]byte(s))
+
+func BenchmarkJsonex_UnmarshalString_Large(b *testing.B) {
+	input := string(largeJSON)
+	var result map[string]interface{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := UnmarshalString(input, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// First selection benchmarks: the cost must follow the value size, not the input size
+
+func BenchmarkJsonex_Unmarshal_First(b *testing.B) {
+	value := `{"id": 1, "items": [1, 2, 3]}`
+	for _, size := range []int{1 << 10, 1 << 20, 16 << 20} {
+		data := []byte(value + strings.Repeat(`{"filler": [true, false]} `, size/26))
+		b.Run(fmt.Sprintf("%dKB", size>>10), func(b *testing.B) {
+			var result map[string]interface{}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := Unmarshal(data, &result, WithSelection(First)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 // Memory allocation benchmarks
 
 func BenchmarkJsonex_Unmarshal_Small_Allocs(b *testing.B) {
@@ -256,6 +295,18 @@ func BenchmarkJsonex_Unmarshal_WithOptions(b *testing.B) {
 	}
 }
 
+func BenchmarkJsonex_Unmarshal_Large_BufferSize(b *testing.B) {
+	// Decoding into a raw message leaves the allocations of the parser itself
+	var result json.RawMessage
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Unmarshal(largeJSON, &result, WithBufferSize(1<<20)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkJsonex_Unmarshal_DefaultOptions(b *testing.B) {
 	var result map[string]interface{}
 	b.ResetTimer()
@@ -288,6 +339,26 @@ func BenchmarkJsonex_Decoder_MultipleObjects(b *testing.B) {
 	}
 }
 
+func BenchmarkJsonex_Decoder_MultipleObjects_Reset(b *testing.B) {
+	input := `{"a":1} garbage {"b":2} more {"c":3}`
+	reader := strings.NewReader(input)
+	decoder := New(reader)
+
+	var result map[string]interface{}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		reader.Reset(input)
+		decoder.Reset(reader)
+
+		for range 3 {
+			if err := decoder.Decode(&result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
 // Edge case benchmarks
 
 func BenchmarkJsonex_Unmarshal_EmptyObject(b *testing.B) {
@@ -311,3 +382,136 @@ func BenchmarkJsonex_Unmarshal_EmptyArray(b *testing.B) {
 		}
 	}
 }
+
+// No-JSON rejection benchmarks
+
+var noJSONInput = []byte(strings.Repeat("plain log text without any structured payload\n", 1<<20/46))
+
+func BenchmarkJsonex_Unmarshal_NoJSON(b *testing.B) {
+	var result interface{}
+	b.SetBytes(int64(len(noJSONInput)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Unmarshal(noJSONInput, &result); err == nil {
+			b.Fatal("expected error")
+		}
+	}
+}
+
+var manyBracketsInput = []byte(strings.Repeat("log [", 1<<16) + `{"a": 1}`)
+
+func BenchmarkJsonex_Unmarshal_ManyBrackets(b *testing.B) {
+	var result interface{}
+	b.SetBytes(int64(len(manyBracketsInput)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Unmarshal(manyBracketsInput, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJsonex_Decoder_NoJSON(b *testing.B) {
+	reader := bytes.NewReader(noJSONInput)
+	var result interface{}
+	b.SetBytes(int64(len(noJSONInput)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(noJSONInput)
+		if err := New(reader).Decode(&result); err != io.EOF {
+			b.Fatalf("expected io.EOF, got %v", err)
+		}
+	}
+}
+
+func BenchmarkJsonex_Decoder_NoJSON_MaxGarbagePrefix(b *testing.B) {
+	reader := bytes.NewReader(noJSONInput)
+	var result interface{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(noJSONInput)
+		if err := New(reader, WithMaxGarbagePrefix(4096)).Decode(&result); !errors.Is(err, ErrNoValidJSON) {
+			b.Fatalf("expected ErrNoValidJSON, got %v", err)
+		}
+	}
+}
+
+// NDJSON streaming benchmarks
+
+const ndjsonLines = 100000
+
+// generateNDJSON builds a newline-delimited stream of small objects. When
+// garbage is true each line starts with a log-style prefix before the object.
+func generateNDJSON(lines int, garbage bool) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < lines; i++ {
+		if garbage {
+			fmt.Fprintf(&buf, "2024-01-01T00:00:%02dZ INFO worker-%d: ", i%60, i%8)
+		}
+		fmt.Fprintf(&buf, `{"id":%d,"level":"info","msg":"request handled","latency":%d.5,"ok":%t}`+"\n", i, i%1000, i%2 == 0)
+	}
+	return buf.Bytes()
+}
+
+// reportPerValue adds ns/value and allocs/value metrics for benchmarks that
+// decode a whole stream per iteration.
+func reportPerValue(b *testing.B, values int, mallocs uint64) {
+	n := float64(b.N) * float64(values)
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/n, "ns/value")
+	b.ReportMetric(float64(mallocs)/n, "allocs/value")
+}
+
+// streamDecoder is satisfied by both Decoder and json.Decoder.
+type streamDecoder interface {
+	Decode(v interface{}) error
+}
+
+func benchmarkNDJSON(b *testing.B, data []byte, newDecoder func(r io.Reader) streamDecoder) {
+	reader := bytes.NewReader(data)
+	var before, after runtime.MemStats
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader.Reset(data)
+		decoder := newDecoder(reader)
+		count := 0
+		for {
+			var result map[string]interface{}
+			err := decoder.Decode(&result)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+			count++
+		}
+		if count != ndjsonLines {
+			b.Fatalf("decoded %d values, expected %d", count, ndjsonLines)
+		}
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+	reportPerValue(b, ndjsonLines, after.Mallocs-before.Mallocs)
+}
+
+func BenchmarkJsonex_Decoder_NDJSON(b *testing.B) {
+	benchmarkNDJSON(b, generateNDJSON(ndjsonLines, true), func(r io.Reader) streamDecoder {
+		return New(r)
+	})
+}
+
+func BenchmarkJsonex_Decoder_NDJSON_Clean(b *testing.B) {
+	benchmarkNDJSON(b, generateNDJSON(ndjsonLines, false), func(r io.Reader) streamDecoder {
+		return New(r)
+	})
+}
+
+func BenchmarkStdLib_Decoder_NDJSON_Clean(b *testing.B) {
+	benchmarkNDJSON(b, generateNDJSON(ndjsonLines, false), func(r io.Reader) streamDecoder {
+		return json.NewDecoder(r)
+	})
+}
@@ -2,12 +2,16 @@ package jsonex
 
 import (
 	"sync"
+	"sync/atomic"
 )
 
 // buffer represents an internal byte buffer (unexported)
 type buffer struct {
 	data []byte
 	pos  int
+
+	budget func(n int) error // charged with the capacity added by grow
+	err    error             // first error returned by budget
 }
 
 // newBuffer creates a new buffer with the specified capacity
@@ -27,6 +31,9 @@ func (b *buffer) grow(n int) {
 	if newCap < len(b.data)+n {
 		newCap = len(b.data) + n
 	}
+	if b.budget != nil && b.err == nil {
+		b.err = b.budget(newCap - cap(b.data))
+	}
 	newData := make([]byte, len(b.data), newCap)
 	copy(newData, b.data)
 	b.data = newData
@@ -58,6 +65,8 @@ func (b *buffer) len() int {
 func (b *buffer) reset() {
 	b.data = b.data[:0]
 	b.pos = 0
+	b.budget = nil
+	b.err = nil
 }
 
 // slice returns a slice of the buffer from start to end
@@ -75,6 +84,26 @@ var bufferPool = sync.Pool{
 	},
 }
 
+// maxPooledBufferSize is the largest buffer capacity kept in the pools (0 means unlimited)
+var maxPooledBufferSize atomic.Int64
+
+// SetMaxPooledBufferSize sets the largest buffer capacity, in bytes, that is returned
+// to the package-wide buffer pools, including the read buffers of closed Decoders.
+// Larger buffers are left to the garbage collector, which bounds the memory retained
+// by the pools under high concurrency. A value of 0 or less removes the limit (default)
+func SetMaxPooledBufferSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	maxPooledBufferSize.Store(int64(n))
+}
+
+// shouldPool checks if a buffer is small enough to be returned to the pool
+func shouldPool(b *buffer) bool {
+	limit := maxPooledBufferSize.Load()
+	return limit == 0 || int64(cap(b.data)) <= limit
+}
+
 // getBuffer gets a buffer from the pool
 func getBuffer() *buffer {
 	return bufferPool.Get().(*buffer)
@@ -83,5 +112,29 @@ func getBuffer() *buffer {
 // putBuffer returns a buffer to the pool
 func putBuffer(b *buffer) {
 	b.reset()
-	bufferPool.Put(b)
+	if shouldPool(b) {
+		bufferPool.Put(b)
+	}
+}
+
+// scannerBufferPool provides read buffers of scanners, returned by Decoder.Close
+var scannerBufferPool sync.Pool
+
+// getScannerBuffer gets a read buffer of size bytes, from the pool if a pooled one is
+// large enough. The buffer is returned by reference, so that putting it back does not allocate
+func getScannerBuffer(size int) *[]byte {
+	if b, ok := scannerBufferPool.Get().(*[]byte); ok && cap(*b) >= size {
+		*b = (*b)[:size]
+		return b
+	}
+	b := make([]byte, size)
+	return &b
+}
+
+// putScannerBuffer returns a read buffer to the pool, unless it exceeds the pooled size limit
+func putScannerBuffer(b *[]byte) {
+	if limit := maxPooledBufferSize.Load(); limit > 0 && int64(cap(*b)) > limit {
+		return
+	}
+	scannerBufferPool.Put(b)
 }
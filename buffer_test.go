@@ -2,6 +2,7 @@ package jsonex
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -137,3 +138,48 @@ func TestBufferPool(t *testing.T) {
 
 	putBuffer(buf2)
 }
+
+func TestSetMaxPooledBufferSize(t *testing.T) {
+	defer SetMaxPooledBufferSize(0)
+	SetMaxPooledBufferSize(8192)
+
+	small := newBuffer(4096)
+	large := newBuffer(1 << 20)
+	if !shouldPool(small) {
+		t.Error("shouldPool(4096) = false, expected true")
+	}
+	if shouldPool(large) {
+		t.Error("shouldPool(1MB) = true, expected false")
+	}
+
+	// An oversized buffer never comes back from the pool
+	putBuffer(large)
+	for i := 0; i < 10; i++ {
+		buf := getBuffer()
+		if buf == large {
+			t.Fatal("getBuffer() returned a buffer above the threshold")
+		}
+		defer putBuffer(buf)
+	}
+
+	// Removing the limit pools every buffer again
+	SetMaxPooledBufferSize(0)
+	if !shouldPool(large) {
+		t.Error("shouldPool(1MB) without limit = false, expected true")
+	}
+}
+
+func TestParser_GetValueBuffer(t *testing.T) {
+	p := newParser(strings.NewReader(""), applyOptions(WithBufferSize(1<<16)))
+	buf := p.getValueBuffer()
+	defer putBuffer(buf)
+	if cap(buf.data) < 1<<16 {
+		t.Errorf("getValueBuffer() capacity = %d, expected at least %d", cap(buf.data), 1<<16)
+	}
+
+	// Parsers of a byte slice never need buffers larger than the slice
+	p = newBytesParser([]byte(`{"a": 1}`), position{}, applyOptions(WithBufferSize(1<<20)))
+	if len(p.scanner.buffer) != 8 {
+		t.Errorf("scanner buffer size = %d, expected 8", len(p.scanner.buffer))
+	}
+}
@@ -0,0 +1,53 @@
+package jsonex
+
+import (
+	"io"
+)
+
+// unquoteCSV replaces doubled quotes ("") used by CSV quoting with a single quote
+func unquoteCSV(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	lastQuote := false
+	for _, b := range data {
+		result, lastQuote = appendUnquotedCSV(result, b, lastQuote)
+	}
+	return result
+}
+
+// appendUnquotedCSV appends b to dst unless it is the second quote of a doubled pair
+func appendUnquotedCSV(dst []byte, b byte, lastQuote bool) ([]byte, bool) {
+	if b != '"' {
+		return append(dst, b), false
+	}
+	if lastQuote {
+		return dst, false
+	}
+	return append(dst, b), true
+}
+
+// csvReader un-doubles CSV quotes while reading from the underlying reader
+type csvReader struct {
+	reader    io.Reader
+	lastQuote bool
+}
+
+// newCSVReader creates a reader that removes CSV quote doubling
+func newCSVReader(reader io.Reader) *csvReader {
+	return &csvReader{reader: reader}
+}
+
+func (r *csvReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.reader.Read(p)
+
+		// Compact in place; the output is never longer than the input
+		out := p[:0]
+		for _, b := range p[:n] {
+			out, r.lastQuote = appendUnquotedCSV(out, b, r.lastQuote)
+		}
+
+		if len(out) > 0 || err != nil {
+			return len(out), err
+		}
+	}
+}
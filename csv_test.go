@@ -0,0 +1,66 @@
+package jsonex
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestUnquoteCSV(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"{""a"":1}"`, `"{"a":1}"`},
+		{`""""`, `""`},
+		{`"""`, `""`},
+		{`no quotes`, `no quotes`},
+	}
+
+	for _, test := range tests {
+		result := string(unquoteCSV([]byte(test.input)))
+		if result != test.expected {
+			t.Errorf("unquoteCSV(%s) = %s, expected %s", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestWithCSVQuoting_Unmarshal(t *testing.T) {
+	data := []byte(`id,payload` + "\n" + `1,"{""a"":1,""b"":""x""}"`)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithCSVQuoting(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["a"] != float64(1) || result["b"] != "x" {
+		t.Errorf("Unexpected result: %v", result)
+	}
+
+	// Without the option the doubled quotes make the object invalid
+	if err := Unmarshal(data, &result); err == nil {
+		t.Error("Expected error without WithCSVQuoting")
+	}
+}
+
+func TestWithCSVQuoting_Decoder(t *testing.T) {
+	input := `1,"{""a"":1}"` + "\n" + `2,"[""x"",""""]"`
+
+	// Read one byte at a time so that doubled quotes span read boundaries
+	decoder := New(iotest.OneByteReader(strings.NewReader(input)), WithCSVQuoting(true))
+
+	var obj map[string]interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		t.Fatalf("First Decode failed: %v", err)
+	}
+	if obj["a"] != float64(1) {
+		t.Errorf("Unexpected first result: %v", obj)
+	}
+
+	var arr []interface{}
+	if err := decoder.Decode(&arr); err != nil {
+		t.Fatalf("Second Decode failed: %v", err)
+	}
+	if len(arr) != 2 || arr[0] != "x" || arr[1] != "" {
+		t.Errorf("Unexpected second result: %v", arr)
+	}
+}
@@ -1,6 +1,8 @@
 package jsonex
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 )
@@ -9,51 +11,303 @@ import (
 type Decoder struct {
 	parser  *parser
 	options options
+	err     error        // returned by the next Decode instead of a value
+	tokens  []tokenFrame // objects and arrays opened by Token and not closed yet
+	skipped []byte       // garbage skipped before the most recent value, with WithKeepSkipped
 }
 
 // New creates a new Decoder that reads from r
 func New(r io.Reader, opts ...Option) *Decoder {
+	return NewMulti([]io.Reader{r}, opts...)
+}
+
+// NewMulti creates a new Decoder that reads from readers sequentially as one stream
+// With WithValuePerReader, a value is never allowed to span two readers
+func NewMulti(readers []io.Reader, opts ...Option) *Decoder {
 	options := applyOptions(opts...)
+	if len(readers) == 0 {
+		readers = []io.Reader{io.MultiReader()}
+	}
+
+	// The size limit applies to the readers together
+	limit := newInputLimit(options)
+	wrapped := make([]io.Reader, len(readers))
+	for i, r := range readers {
+		if limit != nil {
+			r = &limitReader{reader: r, limit: limit}
+		}
+		wrapped[i] = wrapReader(r, options)
+	}
+	readers = wrapped
+
+	parser := newParser(readers[0], options)
+	parser.scanner.nextReaders = readers[1:]
+	parser.scanner.valuePerReader = options.valuePerReader
+	parser.scanner.perLine = options.perLine
+	parser.scanner.keepSkipped = options.keepSkipped
+	parser.scanner.garbageLimit = int64(options.maxGarbage)
+
 	return &Decoder{
-		parser:  newParser(r, options),
+		parser:  parser,
 		options: options,
 	}
 }
 
+// Reset makes the Decoder read from r as a new input, discarding buffered input, a
+// pending error and the objects and arrays opened by Token. The read buffer and the
+// options are kept, so a Decoder can be reused for many messages without allocating
+// a new one for each
+func (d *Decoder) Reset(r io.Reader) {
+	if limit := newInputLimit(d.options); limit != nil {
+		r = &limitReader{reader: r, limit: limit}
+	}
+	d.parser.scanner.reset(wrapReader(r, d.options), d.options.bufferSize)
+	d.parser.scanner.garbageLimit = int64(d.options.maxGarbage)
+	d.parser.depth = 0
+	d.parser.state = stateValue
+	d.err = nil
+	d.tokens = d.tokens[:0]
+	d.skipped = d.skipped[:0]
+}
+
+// Close returns the read buffer of the Decoder to a pool shared by all Decoders and
+// discards the remaining input, so that services decoding many small messages cause
+// less garbage collection. Afterwards Decode reports io.EOF until Reset gives the
+// Decoder a new input. The underlying readers are not closed
+func (d *Decoder) Close() {
+	d.parser.scanner.release()
+	d.parser.state = stateEnd
+	d.err = nil
+	d.tokens = d.tokens[:0]
+	d.skipped = d.skipped[:0]
+}
+
 // Decode reads the next JSON-encoded value from its input and stores it in the value pointed to by v
-// The behavior is similar to json.Decoder.Decode but only accepts objects and arrays
+// The behavior is similar to json.Decoder.Decode but only accepts objects and arrays,
+// unless WithAllowScalars also accepts strings, numbers, booleans and null.
+// It returns io.EOF once no further value starts in the input, and an *Error of type
+// ErrEOF for a value cut off by the end of the input
 func (d *Decoder) Decode(v interface{}) error {
+	if err := d.err; err != nil {
+		d.err = nil
+		return err
+	}
+
+	// Values inside an object or array opened by Token are parsed strictly
+	if len(d.tokens) > 0 {
+		err := d.decodeToken(v)
+		if err != nil {
+			d.resetTokens()
+		}
+		return err
+	}
+
 	// Extract the next JSON object or array
-	jsonBytes, err := d.parser.parseNext()
+	jsonBytes, err := d.extract()
+	for (d.options.perLine || d.options.allowScalars) && isCandidateError(err) {
+		// Brackets in log text are not errors when looking for the first value of a line,
+		// nor are words that merely start like true, false or null
+		jsonBytes, err = d.extract()
+	}
 	if err != nil {
 		return err
 	}
+	d.takeSkipped()
+
+	record := d.parser.scanner.record
+	if d.options.lookahead > 0 {
+		if jsonBytes, record, err = d.lookahead(jsonBytes); err != nil {
+			return err
+		}
+	}
+
+	// Only the first value of each line is extracted
+	if d.options.perLine {
+		d.parser.scanner.skipLine()
+	}
 
 	// Use standard library to decode the extracted JSON
-	return json.Unmarshal(jsonBytes, v)
+	return decodeSource(record, jsonBytes, v, d.options)
+}
+
+// extract extracts the next top-level value, between the markers of WithDelimiters if set
+func (d *Decoder) extract() ([]byte, error) {
+	if d.options.startMarker != "" {
+		return d.parser.parseDelimitedNext()
+	}
+	return d.parser.parseNext()
 }
 
-// More methods can be added here for compatibility with json.Decoder if needed
+// DecodeContext is like Decode but stops with ctx.Err() once ctx is done. The context
+// is checked before every read from the input, so a Read call that blocks is not interrupted
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	d.parser.scanner.ctx = ctx
+	defer func() { d.parser.scanner.ctx = nil }()
+	return d.Decode(v)
+}
 
-// Buffered returns a reader of the data remaining in the Decoder's buffer
-// This can be useful for reading any remaining data after JSON parsing
-func (d *Decoder) Buffered() io.Reader {
-	// For now, we don't implement buffering
-	// This would require more complex scanner state management
+// All decodes every remaining value in the stream and sends it to out, then closes out
+// It returns nil at the end of the input and the first other error otherwise. Values are
+// sent as they are decoded, so All is typically run in its own goroutine
+func (d *Decoder) All(out chan<- json.RawMessage) error {
+	defer close(out)
+	for {
+		var value json.RawMessage
+		if err := d.Decode(&value); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		out <- value
+	}
+}
+
+// lookahead replaces the extracted value with a longer one starting within the
+// lookahead window after its end, and returns the chosen value with its source bytes.
+// A value that is not longer is pushed back for the next Decode
+func (d *Decoder) lookahead(jsonBytes []byte) ([]byte, []byte, error) {
+	scanner := d.parser.scanner
+	// The record is reused by the next mark, so keep a copy of the current best
+	record := append([]byte(nil), scanner.record...)
+
+	// A value cut off by the end of the input is reported by the next Decode, unless
+	// a longer value found inside it replaces the extracted one
+	var truncated error
+
+	limit := scanner.offset + int64(d.options.lookahead)
+	for {
+		startByte, found, err := scanner.findJSONStartBefore(limit)
+		if err != nil || !found {
+			// Read errors are left for the next Decode to report
+			d.err = truncated
+			return jsonBytes, record, nil
+		}
+
+		candidate, err := d.parser.parseFrom(startByte)
+		if err != nil {
+			if isAbortError(err) {
+				return nil, nil, err
+			}
+			if !isCandidateError(err) && truncated == nil {
+				truncated = err
+			}
+			continue
+		}
+		if len(candidate) <= len(jsonBytes) {
+			scanner.rewind(0)
+			d.err = truncated
+			return jsonBytes, record, nil
+		}
+		jsonBytes = candidate
+		record = append(record[:0], scanner.record...)
+		limit = scanner.offset + int64(d.options.lookahead)
+		truncated = nil
+	}
+}
+
+// Validate strictly parses the next JSON value in the stream without decoding it
+// Unlike Decode, no garbage is skipped and a failed value is not retried, so the returned
+// *Error points at the first invalid byte. The stream is left right after that byte.
+// It returns io.EOF when only whitespace remains
+func (d *Decoder) Validate() error {
+	p := d.parser
+	p.depth = 0
+	p.state = stateValue
+
+	if err := p.scanner.skipWhitespace(); err != nil {
+		return err
+	}
+	buf := p.getValueBuffer()
+	defer putBuffer(buf)
+
+	err := p.parseElement(buf)
+	if err == nil {
+		err = buf.err
+	}
+	if err == io.EOF {
+		return newEOFError(p.scanner.position(), "unexpected end of input in JSON value")
+	}
+	if err != nil {
+		return err
+	}
+	p.state = stateEnd
 	return nil
 }
 
+// LastSkipped returns the bytes skipped as garbage before the most recent top-level
+// value read by Decode or Token, including failed candidates, since the value before
+// it. Skipped bytes are only collected with WithKeepSkipped; otherwise it returns nil.
+// The slice is only valid until the next value is read
+func (d *Decoder) LastSkipped() []byte {
+	return d.skipped
+}
+
+// takeSkipped makes the garbage skipped before the value just read the result of LastSkipped
+// The WithMaxGarbagePrefix limit only applies before the first value
+func (d *Decoder) takeSkipped() {
+	s := d.parser.scanner
+	s.garbageLimit = 0
+	if s.keepSkipped {
+		d.skipped, s.skipped = s.skipped, d.skipped[:0]
+	}
+}
+
+// InputOffset returns the offset in the input stream of the byte following the most
+// recently decoded value, including any garbage skipped before it
+func (d *Decoder) InputOffset() int64 {
+	return d.parser.scanner.offset
+}
+
+// State returns a description of where the parser is, such as "in object, expecting value"
+// This is intended for debugging streams that stop or fail in the middle of a value
+func (d *Decoder) State() string {
+	return d.parser.state.String()
+}
+
+// More reports whether another JSON value starts in the remaining input: an object or
+// an array, or also a scalar with WithAllowScalars
+// Garbage before it is consumed, but the value itself is left for the next Decode.
+// Inside an object or array opened by Token, it reports whether another entry follows.
+// Like json.Decoder.More, it waits for input and reports false on read errors
+func (d *Decoder) More() bool {
+	if len(d.tokens) > 0 {
+		return d.moreTokens()
+	}
+	if marker := d.options.startMarker; marker != "" {
+		return d.parser.scanner.skipTo([]byte(marker)) == nil
+	}
+	_, err := d.parser.scanner.findJSONStart()
+	return err == nil
+}
+
+// Buffered returns a reader of the input remaining after the most recently decoded value:
+// the data in the Decoder's buffer followed by the rest of the underlying readers.
+// This is useful for protocols where other data follows the JSON. The reader is only
+// valid until the next call on the Decoder. Reading from it does not advance the Decoder,
+// which still holds the buffered data, but bytes it reads from the underlying readers
+// are no longer seen by the Decoder, so it is meant to take over the rest of the input
+func (d *Decoder) Buffered() io.Reader {
+	s := d.parser.scanner
+	readers := []io.Reader{bytes.NewReader(s.buffer[s.pos:s.size])}
+	if !s.eof {
+		if !s.atBoundary {
+			readers = append(readers, s.reader)
+		}
+		readers = append(readers, s.nextReaders...)
+	}
+	return io.MultiReader(readers...)
+}
+
 // DisallowUnknownFields causes the Decoder to return an error when the destination
 // is a struct and the input contains object keys which do not match any
 // non-ignored, exported fields in the destination
 func (d *Decoder) DisallowUnknownFields() {
-	// This would require integration with the standard library's decoder options
-	// For now, we don't implement this feature
+	d.options.disallowUnknown = true
 }
 
 // UseNumber causes the Decoder to unmarshal a number into an interface{} as a
-// Number instead of as a float64
+// json.Number instead of as a float64
 func (d *Decoder) UseNumber() {
-	// This would require integration with the standard library's decoder options
-	// For now, we don't implement this feature
+	d.options.useNumber = true
 }
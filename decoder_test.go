@@ -1,8 +1,15 @@
 package jsonex
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestDecoder_BasicObject(t *testing.T) {
@@ -118,6 +125,45 @@ func TestDecoder_MultipleObjects(t *testing.T) {
 	}
 }
 
+func TestDecoder_ConcatenatedValues(t *testing.T) {
+	// Adjacent values without any delimiter are decoded one by one
+	input := `{"a":1}{"b":2}[1,2]`
+	expected := []interface{}{
+		map[string]interface{}{"a": float64(1)},
+		map[string]interface{}{"b": float64(2)},
+		[]interface{}{float64(1), float64(2)},
+	}
+
+	tests := map[string]struct {
+		reader func() io.Reader
+		opts   []Option
+	}{
+		"Whole input": {func() io.Reader { return strings.NewReader(input) }, nil},
+		"One byte":    {func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) }, nil},
+		"Tiny buffer": {func() io.Reader { return strings.NewReader(input) }, []Option{WithBufferSize(1)}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			decoder := New(test.reader(), test.opts...)
+			for i, want := range expected {
+				var v interface{}
+				if err := decoder.Decode(&v); err != nil {
+					t.Fatalf("Decode %d failed: %v", i, err)
+				}
+				if !reflect.DeepEqual(v, want) {
+					t.Errorf("Decode %d = %v, expected %v", i, v, want)
+				}
+			}
+
+			var v interface{}
+			if err := decoder.Decode(&v); err != io.EOF {
+				t.Errorf("Decode after last value = %v, expected io.EOF", err)
+			}
+		})
+	}
+}
+
 func TestDecoder_ArrayStringVsMap(t *testing.T) {
 	// Test decoder behavior with arrays vs maps containing array-like strings
 	// Note: Decoder uses parseNext() which finds FIRST valid JSON, not longest
@@ -221,3 +267,936 @@ func TestDecoder_FirstVsLongestJSON(t *testing.T) {
 	})
 }
 
+func TestDecoder_RecoverAfterError(t *testing.T) {
+	decoder := New(strings.NewReader(`{bad} {"good":1}`))
+
+	var result map[string]interface{}
+	if err := decoder.Decode(&result); err == nil {
+		t.Fatal("Expected error for malformed first object")
+	}
+
+	result = nil
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode after error failed: %v", err)
+	}
+	if result["good"] != float64(1) {
+		t.Errorf("Expected good=1, got %v", result)
+	}
+}
+
+func TestDecoder_RecoverInsideFailedValue(t *testing.T) {
+	// The outer array is never closed, so the nested object must be found
+	// by rescanning from the byte after the failed '['
+	decoder := New(strings.NewReader(`[{"inner": true}`))
+
+	var first interface{}
+	if err := decoder.Decode(&first); err == nil {
+		t.Fatal("Expected error for unterminated array")
+	}
+
+	var result map[string]interface{}
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode after error failed: %v", err)
+	}
+	if result["inner"] != true {
+		t.Errorf("Expected inner=true, got %v", result)
+	}
+}
+
+func TestDecoder_EOF(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		opts      []Option
+		truncated bool
+	}{
+		{"clean end", `{"a":1}`, nil, false},
+		{"trailing garbage", `{"a":1} garbage }`, nil, false},
+		{"truncated value", `{"a":1} {"b"`, nil, true},
+		{"truncated value with lookahead", `{"a":1} {"b"`, []Option{WithLookahead(16)}, true},
+		{"trailing garbage with lookahead", `{"a":1} x`, []Option{WithLookahead(16)}, false},
+		{"truncated line", "{\"a\":1}\ngarbage [", []Option{WithPerLineExtraction(true)}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := New(strings.NewReader(test.input), test.opts...)
+			var v interface{}
+			if err := decoder.Decode(&v); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			err := decoder.Decode(&v)
+			if test.truncated {
+				jsonErr, ok := err.(*Error)
+				if !ok || jsonErr.Type != ErrEOF {
+					t.Fatalf("Decode of truncated value = %v, expected ErrEOF error", err)
+				}
+				err = decoder.Decode(&v)
+			}
+
+			// io.EOF must be returned as is so that err == io.EOF ends a loop
+			if err != io.EOF {
+				t.Errorf("Decode at end of input = %#v, expected io.EOF", err)
+			}
+			if err := decoder.Decode(&v); err != io.EOF {
+				t.Errorf("Decode after io.EOF = %#v, expected io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestDecoder_State(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": `, "in object, expecting value"},
+		{`{"a"`, "in object, expecting ':'"},
+		{`{"a": 1`, "in object, expecting ',' or '}'"},
+		{`{"a": [1, `, "in array, expecting value"},
+		{`[1 2]`, "in array, expecting ',' or ']'"},
+	}
+
+	for _, test := range tests {
+		decoder := New(strings.NewReader(test.input))
+		if state := decoder.State(); state != "searching for value" {
+			t.Errorf("Initial State() = %q", state)
+		}
+
+		var result interface{}
+		if err := decoder.Decode(&result); err == nil {
+			t.Fatalf("Expected error for %q", test.input)
+		}
+		if state := decoder.State(); state != test.expected {
+			t.Errorf("State() after %q = %q, expected %q", test.input, state, test.expected)
+		}
+	}
+
+	decoder := New(strings.NewReader(`{"a": 1}`))
+	var result interface{}
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if state := decoder.State(); state != "value complete" {
+		t.Errorf("State() after Decode = %q, expected %q", state, "value complete")
+	}
+}
+
+func TestDecoder_NewMulti(t *testing.T) {
+	readers := func() []io.Reader {
+		return []io.Reader{
+			strings.NewReader(`garbage {"a": 1} {"partial": `),
+			strings.NewReader(`"x"} {"b": 2}`),
+		}
+	}
+
+	t.Run("Values may span readers by default", func(t *testing.T) {
+		decoder := NewMulti(readers())
+
+		expected := []string{"a", "partial", "b"}
+		for _, key := range expected {
+			var result map[string]interface{}
+			if err := decoder.Decode(&result); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if _, ok := result[key]; !ok {
+				t.Errorf("Expected key %q, got %v", key, result)
+			}
+		}
+
+		var result interface{}
+		if err := decoder.Decode(&result); err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+	})
+
+	t.Run("WithValuePerReader", func(t *testing.T) {
+		decoder := NewMulti(readers(), WithValuePerReader(true))
+
+		var first map[string]interface{}
+		if err := decoder.Decode(&first); err != nil {
+			t.Fatalf("First Decode failed: %v", err)
+		}
+		if first["a"] != float64(1) {
+			t.Errorf("Unexpected first result: %v", first)
+		}
+
+		var partial interface{}
+		err := decoder.Decode(&partial)
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrEOF {
+			t.Fatalf("Expected EOF error for value crossing readers, got %v", err)
+		}
+
+		var second map[string]interface{}
+		if err := decoder.Decode(&second); err != nil {
+			t.Fatalf("Decode after boundary failed: %v", err)
+		}
+		if second["b"] != float64(2) {
+			t.Errorf("Unexpected second result: %v", second)
+		}
+	})
+
+	t.Run("No readers", func(t *testing.T) {
+		var result interface{}
+		if err := NewMulti(nil).Decode(&result); err != io.EOF {
+			t.Errorf("Expected io.EOF, got %v", err)
+		}
+	})
+}
+
+func TestDecoder_WithTee(t *testing.T) {
+	input := `garbage {"first": 1} between {"second": 2} trailing`
+
+	// With a one-byte buffer the scanner reads exactly what it consumes
+	var tee strings.Builder
+	decoder := New(strings.NewReader(input), WithTee(&tee), WithBufferSize(1))
+
+	var result map[string]interface{}
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if expected := `garbage {"first": 1}`; tee.String() != expected {
+		t.Errorf("Teed %q, expected %q", tee.String(), expected)
+	}
+
+	// Draining the decoder tees the whole input
+	for decoder.Decode(&result) != io.EOF {
+	}
+	if tee.String() != input {
+		t.Errorf("Teed %q, expected %q", tee.String(), input)
+	}
+}
+
+func TestDecoder_WithPerLineExtraction(t *testing.T) {
+	input := strings.Join([]string{
+		`2024-01-01 INFO {"msg":"first"} {"msg":"ignored"}`,
+		`2024-01-01 DEBUG no payload here`,
+		`2024-01-01 WARN [worker-1] {"msg":"second", "tags": ["a"]}`,
+		`2024-01-01 ERROR {"msg": "truncated`,
+		``,
+		`2024-01-01 INFO {"msg":"split",`,
+		`"key": 1}`,
+		`[1, 2] trailing {"msg":"ignored"}`,
+		`2024-01-01 INFO {"msg":"last"}`,
+	}, "\n")
+
+	decoder := New(strings.NewReader(input), WithPerLineExtraction(true))
+	var results []interface{}
+	for {
+		var v interface{}
+		err := decoder.Decode(&v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		results = append(results, v)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"msg": "first"},
+		map[string]interface{}{"msg": "second", "tags": []interface{}{"a"}},
+		[]interface{}{float64(1), float64(2)},
+		map[string]interface{}{"msg": "last"},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Decode = %v, expected %v", results, expected)
+	}
+
+	// Without the option, values are extracted regardless of lines
+	decoder = New(strings.NewReader(`{"a":1} {"b":2}`+"\n"+`{"c":`), WithPerLineExtraction(false))
+	var v interface{}
+	for _, key := range []string{"a", "b"} {
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if _, ok := v.(map[string]interface{})[key]; !ok {
+			t.Errorf("Decode = %v, expected key %q", v, key)
+		}
+	}
+}
+
+func TestDecoder_WithLookahead(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		lookahead int
+		expected  []string
+	}{
+		{"Longer value within window", `{"a":1} - {"b":[1,2,3]}`, 8, []string{`{"b":[1,2,3]}`}},
+		{"Longer value beyond window", `{"a":1} -------- {"b":[1,2,3]}`, 8, []string{`{"a":1}`, `{"b":[1,2,3]}`}},
+		{"Shorter value is kept", `{"b":[1,2,3]} {"a":1}`, 8, []string{`{"b":[1,2,3]}`, `{"a":1}`}},
+		{"Chain of longer values", `[1] [1,2] [1,2,3]`, 4, []string{`[1,2,3]`}},
+		{"Failed candidate in window", `{"a":1} {"x": {"b":[1,2,3]}`, 8, []string{`{"b":[1,2,3]}`}},
+		{"Without lookahead", `{"a":1} {"b":[1,2,3]}`, 0, []string{`{"a":1}`, `{"b":[1,2,3]}`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := New(strings.NewReader(test.input), WithLookahead(test.lookahead))
+			var results []string
+			for {
+				var v interface{}
+				err := decoder.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Decode failed: %v", err)
+				}
+				encoded, _ := json.Marshal(v)
+				results = append(results, string(encoded))
+			}
+			if !reflect.DeepEqual(results, test.expected) {
+				t.Errorf("Decode = %v, expected %v", results, test.expected)
+			}
+		})
+	}
+
+	// Raw values are the source bytes of the chosen value
+	var raw map[string]json.RawMessage
+	if err := New(strings.NewReader(`{"a": 1} {"b": [1, 2]}`), WithLookahead(4)).Decode(&raw); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if string(raw["b"]) != `[1, 2]` {
+		t.Errorf("Decode = %s, expected source bytes of the longer value", raw)
+	}
+}
+
+// countingReader records how many bytes were read from the underlying reader
+type countingReader struct {
+	reader io.Reader
+	n      int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestDecoder_DoesNotOverRead(t *testing.T) {
+	const bufferSize = 1024
+	rest := strings.Repeat(`{"filler": [1, 2, 3]} `, 1<<16)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"Small object", `{"a": 1}`},
+		{"Garbage prefix", `log line ` + `{"a": 1}`},
+		{"Value larger than buffer", `{"s": "` + strings.Repeat("x", 5*bufferSize) + `"}`},
+		{"Trailing number in array", `[1, 2, 3]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := &countingReader{reader: strings.NewReader(test.value + rest)}
+			decoder := New(reader, WithBufferSize(bufferSize))
+
+			var result interface{}
+			if err := decoder.Decode(&result); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if limit := len(test.value) + bufferSize; reader.n > limit {
+				t.Errorf("Read %d bytes for a %d byte value, expected at most %d", reader.n, len(test.value), limit)
+			}
+		})
+	}
+}
+
+func TestDecoder_Validate(t *testing.T) {
+	t.Run("Valid stream", func(t *testing.T) {
+		decoder := New(strings.NewReader(" {\"a\": 1}\n[1, 2]\n\"s\" 42 "))
+		for i := 0; i < 4; i++ {
+			if err := decoder.Validate(); err != nil {
+				t.Fatalf("Validate #%d = %v, expected nil", i, err)
+			}
+		}
+		if err := decoder.Validate(); err != io.EOF {
+			t.Errorf("Validate at end = %v, expected io.EOF", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		input   string
+		errType ErrorType
+		line    int
+		column  int
+		offset  int64
+	}{
+		{"Garbage is not skipped", `log {"a": 1}`, ErrSyntax, 1, 1, 0},
+		{"Missing colon", `{"k" 1}`, ErrSyntax, 1, 6, 5},
+		{"Missing comma", `[1 2]`, ErrSyntax, 1, 4, 3},
+		{"Trailing comma", "{\"a\": 1,\n}", ErrSyntax, 2, 1, 9},
+		{"Invalid escape", `["\q"]`, ErrEscape, 1, 4, 3},
+		{"Invalid hex digit", `["\u00G0"]`, ErrEscape, 1, 7, 6},
+		{"Invalid UTF-8", "[\"ok\xc3\x28\"]", ErrUnicode, 1, 6, 5},
+		{"Invalid literal", `[true, nul]`, ErrSyntax, 1, 11, 10},
+		{"Truncated value", `{"a": [1, 2`, ErrEOF, 1, 12, 11},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := New(strings.NewReader(test.input), WithStrictUTF8(true)).Validate()
+			jsonErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Validate = %v, expected *Error", err)
+			}
+			expected := Position{Offset: test.offset, Line: test.line, Column: test.column}
+			if jsonErr.Type != test.errType || jsonErr.Position != expected {
+				t.Errorf("Validate = %v (%+v), expected %v at %+v", err, jsonErr.Position, test.errType, expected)
+			}
+		})
+	}
+
+	t.Run("Offsets continue across values", func(t *testing.T) {
+		decoder := New(strings.NewReader("{\"a\": 1}\n{\"b\" 2}"))
+		if err := decoder.Validate(); err != nil {
+			t.Fatalf("Validate = %v, expected nil", err)
+		}
+		err := decoder.Validate()
+		jsonErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("Validate = %v, expected *Error", err)
+		}
+		if expected := (Position{Offset: 14, Line: 2, Column: 6}); jsonErr.Position != expected {
+			t.Errorf("Validate position = %+v, expected %+v", jsonErr.Position, expected)
+		}
+	})
+}
+
+func TestDecoder_EscapesMatchUnmarshal(t *testing.T) {
+	inputs := []string{
+		`{"emoji": "\uD83D\uDE00"}`,
+		`log {"emoji": "\uD83D\uDE00", "path": "C:\\temp\\new", "tab": "a\tb"} end`,
+		`["\u3042", "\"quoted\"", "\/slash"]`,
+	}
+
+	for _, input := range inputs {
+		var unmarshaled, decoded interface{}
+		if err := Unmarshal([]byte(input), &unmarshaled); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", input, err)
+		}
+		if err := New(strings.NewReader(input)).Decode(&decoded); err != nil {
+			t.Fatalf("Decode(%s) failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(decoded, unmarshaled) {
+			t.Errorf("Decode(%s) = %q, Unmarshal = %q", input, decoded, unmarshaled)
+		}
+	}
+
+	var result map[string]string
+	if err := New(strings.NewReader(inputs[0])).Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if result["emoji"] != "😀" {
+		t.Errorf("Decode = %q, expected %q", result["emoji"], "😀")
+	}
+}
+
+func TestDecoder_All(t *testing.T) {
+	decoder := New(strings.NewReader(`noise {"a": 1} garbage [2] {"b": 3} trailing garbage`))
+	values := make(chan json.RawMessage)
+	errCh := make(chan error, 1)
+	go func() { errCh <- decoder.All(values) }()
+
+	var results []string
+	for value := range values {
+		results = append(results, string(value))
+	}
+	if err := <-errCh; err != nil {
+		t.Errorf("All returned %v at end of input, expected nil", err)
+	}
+	// Raw messages keep the source bytes
+	expected := []string{`{"a": 1}`, `[2]`, `{"b": 3}`}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("All sent %v, expected %v", results, expected)
+	}
+
+	// A read error is returned after the values before it, and the channel is still closed
+	readErr := io.ErrClosedPipe
+	decoder = New(io.MultiReader(strings.NewReader(`{"a": 1} `), iotest.ErrReader(readErr)))
+	values = make(chan json.RawMessage, 2)
+	if err := decoder.All(values); err != readErr {
+		t.Errorf("All returned %v, expected %v", err, readErr)
+	}
+	results = nil
+	for value := range values {
+		results = append(results, string(value))
+	}
+	if !reflect.DeepEqual(results, []string{`{"a": 1}`}) {
+		t.Errorf("All sent %v before the error", results)
+	}
+}
+
+func TestDecoder_More(t *testing.T) {
+	input := `noise {"a": 1} garbage [2] {"b": 3} trailing garbage`
+	decoder := New(strings.NewReader(input))
+
+	var results []interface{}
+	for decoder.More() {
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		results = append(results, v)
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"a": float64(1)},
+		[]interface{}{float64(2)},
+		map[string]interface{}{"b": float64(3)},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Decoded %v, expected %v", results, expected)
+	}
+
+	// More is idempotent and does not consume the value
+	decoder = New(strings.NewReader(`x {"a": 1}`))
+	if !decoder.More() || !decoder.More() {
+		t.Fatal("More = false, expected true")
+	}
+	var v map[string]interface{}
+	if err := decoder.Decode(&v); err != nil || v["a"] != float64(1) {
+		t.Errorf("Decode after More = %v, %v", v, err)
+	}
+	if decoder.More() {
+		t.Error("More at end of input = true, expected false")
+	}
+
+	// More returns once the start of a value is available from a streaming reader
+	r, w := io.Pipe()
+	decoder = New(r)
+	go func() {
+		_, _ = w.Write([]byte(`partial {"a": `))
+	}()
+	if !decoder.More() {
+		t.Error("More with a pending value = false, expected true")
+	}
+	_ = w.Close()
+}
+
+func TestDecoder_Buffered(t *testing.T) {
+	tests := []struct {
+		name    string
+		readers []io.Reader
+		opts    []Option
+	}{
+		{"all buffered", []io.Reader{strings.NewReader(`x {"a":1}BLOB DATA`)}, nil},
+		{"rest in reader", []io.Reader{strings.NewReader(`x {"a":1}BLOB DATA`)}, []Option{WithBufferSize(10)}},
+		{"rest in next reader", []io.Reader{strings.NewReader(`x {"a":1}BLOB`), strings.NewReader(` DATA`)}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := NewMulti(test.readers, test.opts...)
+			var v map[string]interface{}
+			if err := decoder.Decode(&v); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			rest, err := io.ReadAll(decoder.Buffered())
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(rest) != "BLOB DATA" {
+				t.Errorf("Buffered = %q, expected %q", rest, "BLOB DATA")
+			}
+		})
+	}
+
+	// Nothing remains after the end of the input
+	decoder := New(strings.NewReader(`{"a":1}`))
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if rest, _ := io.ReadAll(decoder.Buffered()); len(rest) != 0 {
+		t.Errorf("Buffered = %q, expected nothing", rest)
+	}
+}
+
+func TestDecoder_UseNumber(t *testing.T) {
+	input := `log {"id": 1234567890123456789, "ratio": 0.5, "list": [10000000000000001]} end`
+
+	for _, opts := range [][]Option{nil, {WithNoStdlib(true)}, {WithIntegerNumbers(true)}} {
+		decoder := New(strings.NewReader(input), opts...)
+		decoder.UseNumber()
+
+		var result map[string]interface{}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if n, ok := result["id"].(json.Number); !ok || n.String() != "1234567890123456789" {
+			t.Errorf("id = %#v, expected json.Number 1234567890123456789", result["id"])
+		}
+		if n, ok := result["ratio"].(json.Number); !ok || n.String() != "0.5" {
+			t.Errorf("ratio = %#v, expected json.Number 0.5", result["ratio"])
+		}
+		list, _ := result["list"].([]interface{})
+		if len(list) != 1 || list[0] != json.Number("10000000000000001") {
+			t.Errorf("list = %#v, expected [json.Number 10000000000000001]", result["list"])
+		}
+	}
+
+	// Typed destinations are unaffected
+	decoder := New(strings.NewReader(`{"id": 42}`))
+	decoder.UseNumber()
+	var typed struct{ ID int64 }
+	if err := decoder.Decode(&typed); err != nil || typed.ID != 42 {
+		t.Errorf("Decode into struct = %v, %v, expected 42", typed, err)
+	}
+}
+
+func TestDecoder_DisallowUnknownFields(t *testing.T) {
+	type target struct {
+		Name string `json:"name"`
+	}
+	input := `log {"name":"x","extra":1} {"name":"y"}`
+
+	decoder := New(strings.NewReader(input))
+	decoder.DisallowUnknownFields()
+
+	var result target
+	if err := decoder.Decode(&result); err == nil || !strings.Contains(err.Error(), "extra") {
+		t.Errorf("Decode with unknown field = %v, expected unknown field error", err)
+	}
+	result = target{}
+	if err := decoder.Decode(&result); err != nil || result.Name != "y" {
+		t.Errorf("Decode = %v, %v, expected name y", result, err)
+	}
+
+	// Unknown fields are accepted by default
+	result = target{}
+	if err := New(strings.NewReader(input)).Decode(&result); err != nil || result.Name != "x" {
+		t.Errorf("Decode without DisallowUnknownFields = %v, %v, expected name x", result, err)
+	}
+}
+
+func TestDecoder_InputOffset(t *testing.T) {
+	input := "garbage {\"a\": 1} more\n[2, 3]   {\"b\": \"é\"} tail"
+	decoder := New(strings.NewReader(input), WithBufferSize(8))
+
+	if offset := decoder.InputOffset(); offset != 0 {
+		t.Errorf("InputOffset before Decode = %d, expected 0", offset)
+	}
+
+	for _, value := range []string{`{"a": 1}`, `[2, 3]`, `{"b": "é"}`} {
+		var result interface{}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		expected := int64(strings.Index(input, value) + len(value))
+		if offset := decoder.InputOffset(); offset != expected {
+			t.Errorf("InputOffset after %s = %d, expected %d", value, offset, expected)
+		}
+	}
+
+	var result interface{}
+	if err := decoder.Decode(&result); err != io.EOF {
+		t.Fatalf("Decode at end = %v, expected io.EOF", err)
+	}
+	if offset := decoder.InputOffset(); offset != int64(len(input)) {
+		t.Errorf("InputOffset at end = %d, expected %d", offset, len(input))
+	}
+}
+
+// endlessReader produces an opening prefix followed by repeated chunks forever
+type endlessReader struct {
+	prefix string
+	chunk  string
+	sent   bool
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		return copy(p, r.prefix), nil
+	}
+	n := 0
+	for n+len(r.chunk) <= len(p) {
+		n += copy(p[n:], r.chunk)
+	}
+	return n, nil
+}
+
+func TestDecoder_DecodeContext(t *testing.T) {
+	t.Run("Cancelled mid-value", func(t *testing.T) {
+		decoder := New(&endlessReader{prefix: `{"a": [`, chunk: `1, `})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		var result interface{}
+		if err := decoder.DecodeContext(ctx, &result); err != context.DeadlineExceeded {
+			t.Errorf("DecodeContext = %v, expected context.DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("Cancelled while skipping garbage", func(t *testing.T) {
+		decoder := New(&endlessReader{chunk: "noise "})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var result interface{}
+		if err := decoder.DecodeContext(ctx, &result); err != context.Canceled {
+			t.Errorf("DecodeContext = %v, expected context.Canceled", err)
+		}
+	})
+
+	t.Run("Not cancelled", func(t *testing.T) {
+		decoder := New(strings.NewReader(`x {"a": 1} y {"b": 2}`))
+		var first, second map[string]interface{}
+		if err := decoder.DecodeContext(context.Background(), &first); err != nil || first["a"] != float64(1) {
+			t.Fatalf("DecodeContext = %v, %v", first, err)
+		}
+
+		// Decode continues the same stream
+		if err := decoder.Decode(&second); err != nil || second["b"] != float64(2) {
+			t.Errorf("Decode after DecodeContext = %v, %v", second, err)
+		}
+	})
+}
+
+func TestDecoder_WithMaxInputSize(t *testing.T) {
+	input := `{"a": 1} {"b": 2} {"c": 3}`
+
+	// The third value ends beyond the limit
+	reader := &countingReader{reader: strings.NewReader(input)}
+	decoder := New(reader, WithMaxInputSize(20), WithBufferSize(4))
+	for i := 0; i < 2; i++ {
+		var result interface{}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("Decode #%d failed: %v", i, err)
+		}
+	}
+
+	var result interface{}
+	err := decoder.Decode(&result)
+	jsonErr, ok := err.(*Error)
+	if !ok || jsonErr.Type != ErrInvalidJSON {
+		t.Fatalf("Decode beyond the limit = %v, expected ErrInvalidJSON", err)
+	}
+	if err := decoder.Decode(&result); err == nil || err == io.EOF {
+		t.Errorf("Decode after the limit = %v, expected the limit error again", err)
+	}
+	if reader.n > 21 {
+		t.Errorf("Read %d bytes, expected at most 21", reader.n)
+	}
+
+	// The limit is shared by the readers of NewMulti
+	decoder = NewMulti([]io.Reader{strings.NewReader(`{"a": 1}`), strings.NewReader(`{"b": 2}`)}, WithMaxInputSize(12))
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if err := decoder.Decode(&result); err == nil {
+		t.Errorf("Decode of the second reader beyond the limit = %v, expected error", result)
+	}
+
+	// Input of exactly the limit is accepted
+	decoder = New(strings.NewReader(input), WithMaxInputSize(len(input)))
+	for {
+		if err := decoder.Decode(&result); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Decode within the limit failed: %v", err)
+		}
+	}
+}
+
+func TestDecoder_WithMaxGarbagePrefix(t *testing.T) {
+	// Input without JSON is rejected after reading the limit, not the whole stream
+	reader := &countingReader{reader: strings.NewReader(strings.Repeat("not json ", 1000))}
+	decoder := New(reader, WithMaxGarbagePrefix(16), WithBufferSize(4))
+	var result interface{}
+	err := decoder.Decode(&result)
+	if !errors.Is(err, ErrNoValidJSON) || errors.Is(err, ErrNoJSON) {
+		t.Fatalf("Decode = %v, expected ErrNoValidJSON", err)
+	}
+	if err := decoder.Decode(&result); !errors.Is(err, ErrNoValidJSON) {
+		t.Errorf("Decode after the limit = %v, expected the limit error again", err)
+	}
+	if reader.n > 24 {
+		t.Errorf("Read %d bytes, expected at most 24", reader.n)
+	}
+
+	// Failed candidates count as garbage, and values after the first one are not bounded
+	decoder = New(strings.NewReader(`log [1, {"a": 1} then a long gap {"b": 2}`), WithMaxGarbagePrefix(8))
+	for _, expected := range []string{`a`, `b`} {
+		var value map[string]int
+		err := decoder.Decode(&value)
+		for isCandidateError(err) {
+			err = decoder.Decode(&value)
+		}
+		if err != nil || len(value) != 1 || value[expected] == 0 {
+			t.Fatalf("Decode = %v, %v, expected key %s", value, err, expected)
+		}
+	}
+
+	// Reset applies the limit to the new input
+	decoder.Reset(strings.NewReader(`a long gap {"c": 3}`))
+	if err := decoder.Decode(&result); !errors.Is(err, ErrNoValidJSON) {
+		t.Errorf("Decode after Reset = %v, expected ErrNoValidJSON", err)
+	}
+
+	// Token is bounded like Decode
+	decoder = New(strings.NewReader(`a long gap [1]`), WithMaxGarbagePrefix(8))
+	if _, err := decoder.Token(); !errors.Is(err, ErrNoValidJSON) {
+		t.Errorf("Token = %v, expected ErrNoValidJSON", err)
+	}
+}
+
+func TestDecoder_EscapeErrorPosition(t *testing.T) {
+	// Escape errors point at the opening quote of the string in the input
+	input := "log line\n  {\"ok\": 1, \"\\ud800\": 2}"
+	decoder := New(strings.NewReader(input), WithStrictUTF8(false), WithDisallowDuplicateKeys())
+	var result interface{}
+	err := decoder.Decode(&result)
+	jsonErr, ok := err.(*Error)
+	if !ok || jsonErr.Type != ErrEscape {
+		t.Fatalf("Decode = %v, expected ErrEscape", err)
+	}
+	expected := Position{Offset: 21, Line: 2, Column: 13}
+	if jsonErr.Position != expected {
+		t.Errorf("Position = %+v, expected %+v", jsonErr.Position, expected)
+	}
+}
+
+func TestDecoder_Reset(t *testing.T) {
+	decoder := New(strings.NewReader(`{"a": 1} {"b": 2}`), WithMaxInputSize(20))
+	var result map[string]int
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	// Leave an array opened by Token
+	decoder.Reset(strings.NewReader(`[1, 2]`))
+	if token, err := decoder.Token(); err != nil || token != Delim('[') {
+		t.Fatalf("Token = %v, %v", token, err)
+	}
+
+	// The new input is read from its start, with positions and the size limit reset
+	decoder.Reset(strings.NewReader("\xef\xbb\xbfnoise\n{\"c\": 3}"))
+	result = nil
+	if err := decoder.Decode(&result); err != nil || result["c"] != 3 {
+		t.Fatalf("Decode after Reset = %v, %v", result, err)
+	}
+	if offset := decoder.InputOffset(); offset != 17 {
+		t.Errorf("InputOffset = %d, expected 17", offset)
+	}
+	if err := decoder.Decode(&result); err != io.EOF {
+		t.Errorf("Decode at end = %v, expected io.EOF", err)
+	}
+
+	decoder.Reset(strings.NewReader("\n[1, }"))
+	var array []int
+	err := decoder.Decode(&array)
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Position.Line != 2 {
+		t.Errorf("Decode after Reset = %v, expected an error on line 2", err)
+	}
+
+	decoder.Reset(strings.NewReader(strings.Repeat(" ", 20) + "[1]"))
+	if err := decoder.Decode(&array); err == nil {
+		t.Errorf("Decode beyond the size limit after Reset = %v, expected error", array)
+	}
+}
+
+func TestDecoder_Close(t *testing.T) {
+	decoder := New(strings.NewReader(`{"a": 1} {"b": 2}`))
+	var result map[string]int
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	decoder.Close()
+
+	// The remaining input is discarded
+	if err := decoder.Decode(&result); err != io.EOF {
+		t.Errorf("Decode after Close = %v, expected io.EOF", err)
+	}
+	if decoder.More() {
+		t.Error("More after Close = true")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		t.Errorf("Token after Close = %v, expected io.EOF", err)
+	}
+
+	// Reset gives a closed Decoder a new input and a read buffer
+	decoder.Reset(strings.NewReader(`noise {"c": 3}`))
+	result = nil
+	if err := decoder.Decode(&result); err != nil || result["c"] != 3 {
+		t.Errorf("Decode after Reset = %v, %v", result, err)
+	}
+	if size := len(decoder.parser.scanner.buffer); size != 4096 {
+		t.Errorf("Read buffer after Reset has %d bytes, expected 4096", size)
+	}
+	decoder.Close()
+	decoder.Close()
+}
+
+func TestDecoder_LastSkipped(t *testing.T) {
+	input := "ts=1 level=info {\"a\": 1}{\"b\": 2}\nts=2 [oops {\"c\": 3} trailer"
+	decoder := New(strings.NewReader(input), WithKeepSkipped())
+
+	// The failed candidate [oops is reported as an error and then counted as garbage
+	expected := []string{"ts=1 level=info ", "", "\nts=2 [oops "}
+	for _, skipped := range expected {
+		var result map[string]int
+		err := decoder.Decode(&result)
+		if _, ok := err.(*Error); ok {
+			err = decoder.Decode(&result)
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if got := string(decoder.LastSkipped()); got != skipped {
+			t.Errorf("LastSkipped = %q, expected %q", got, skipped)
+		}
+	}
+
+	// Token reports the garbage before top-level values as well
+	decoder = New(strings.NewReader(`meta: [1] more: {}`), WithKeepSkipped())
+	for _, skipped := range []string{"meta: ", "meta: ", "meta: ", " more: "} {
+		if _, err := decoder.Token(); err != nil {
+			t.Fatalf("Token failed: %v", err)
+		}
+		if got := string(decoder.LastSkipped()); got != skipped {
+			t.Errorf("LastSkipped = %q, expected %q", got, skipped)
+		}
+	}
+
+	// Skipped bytes are not collected by default
+	decoder = New(strings.NewReader(`garbage {"a": 1}`))
+	var result map[string]int
+	if err := decoder.Decode(&result); err != nil || decoder.LastSkipped() != nil {
+		t.Errorf("LastSkipped without WithKeepSkipped = %q, %v", decoder.LastSkipped(), err)
+	}
+}
+
+func TestDecoder_WithDelimiters(t *testing.T) {
+	input := "[0] <<<JSON {\"a\": 1} JSON>>> noise [9]\n<<<JSON\n[1, 2]\nJSON>>> <<<JSON {\"b\": 2} oops JSON>>> <<<JSON {\"c\": 3} JSON>>>"
+
+	// A small buffer makes markers straddle reads
+	for _, size := range []int{4096, 8} {
+		decoder := New(strings.NewReader(input), WithDelimiters("<<<JSON", "JSON>>>"), WithBufferSize(size))
+		var values []interface{}
+		var errs []error
+		for decoder.More() {
+			var value interface{}
+			if err := decoder.Decode(&value); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			values = append(values, value)
+		}
+
+		expected := []interface{}{map[string]interface{}{"a": 1.0}, []interface{}{1.0, 2.0}, map[string]interface{}{"c": 3.0}}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("buffer %d: Decode returned %v, expected %v", size, values, expected)
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0].Error(), `expected end delimiter "JSON>>>"`) {
+			t.Errorf("buffer %d: Decode errors = %v, expected a missing end delimiter", size, errs)
+		}
+	}
+}
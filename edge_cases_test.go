@@ -1,6 +1,9 @@
 package jsonex
 
 import (
+	"io"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -182,3 +185,170 @@ func TestEdgeCases_JSONLikeStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestEdgeCases_EmptyKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Empty key",
+			data:     `{"": "value"}`,
+			expected: map[string]interface{}{"": "value"},
+		},
+		{
+			name:     "Empty key with garbage",
+			data:     `noise {"": "value", "k": ""} noise`,
+			expected: map[string]interface{}{"": "value", "k": ""},
+		},
+		{
+			name:     "Nested empty key",
+			data:     `{"":{"":[]}}`,
+			expected: map[string]interface{}{"": map[string]interface{}{"": []interface{}{}}},
+		},
+		{
+			// Duplicate keys are allowed by default and the last value wins
+			name:     "Two empty keys",
+			data:     `{"": 1, "": 2}`,
+			expected: map[string]interface{}{"": float64(2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var unmarshaled map[string]interface{}
+			if err := Unmarshal([]byte(test.data), &unmarshaled); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(unmarshaled, test.expected) {
+				t.Errorf("Unmarshal = %v, expected %v", unmarshaled, test.expected)
+			}
+
+			var decoded map[string]interface{}
+			if err := New(strings.NewReader(test.data)).Decode(&decoded); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, test.expected) {
+				t.Errorf("Decode = %v, expected %v", decoded, test.expected)
+			}
+		})
+	}
+
+	// The empty key is a key like any other for the duplicate key options
+	data := []byte(`{"":1,"":2}`)
+	err := ValidateFragment(data, WithDisallowDuplicateKeys())
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Type != ErrSyntax || jsonErr.Position.Offset != 6 || !strings.Contains(jsonErr.Message, `duplicate key ""`) {
+		t.Errorf("ValidateFragment with WithDisallowDuplicateKeys = %v, expected duplicate key error at offset 6", err)
+	}
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithDisallowDuplicateKeys()); err == nil {
+		t.Errorf("Unmarshal with WithDisallowDuplicateKeys = %v, expected error", result)
+	}
+	if err := Unmarshal(data, &result, WithDuplicateKeysAsArray(true)); err != nil {
+		t.Fatalf("Unmarshal with WithDuplicateKeysAsArray failed: %v", err)
+	}
+	if expected := map[string]interface{}{"": []interface{}{float64(1), float64(2)}}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unmarshal with WithDuplicateKeysAsArray = %v, expected %v", result, expected)
+	}
+}
+
+func TestEdgeCases_LineSeparators(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"Raw line separator", "{\"s\": \"a\u2028b\"}", "a\u2028b"},
+		{"Raw paragraph separator", "{\"s\": \"a\u2029b\"}", "a\u2029b"},
+		{"Escaped separators", `{"s": "a\u2028b\u2029c"}`, "a\u2028b\u2029c"},
+		{"Separators in key and garbage", "\u2028noise {\"s\": \"\u2029\", \"\u2028\": 1}\u2029", "\u2029"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The garbage prefix forces the parser path instead of the fast path
+			for _, data := range []string{test.data, "log: " + test.data} {
+				var unmarshaled map[string]interface{}
+				if err := Unmarshal([]byte(data), &unmarshaled); err != nil {
+					t.Fatalf("Unmarshal(%q) failed: %v", data, err)
+				}
+				if unmarshaled["s"] != test.expected {
+					t.Errorf("Unmarshal(%q) = %q, expected %q", data, unmarshaled["s"], test.expected)
+				}
+
+				var decoded map[string]interface{}
+				if err := New(strings.NewReader(data)).Decode(&decoded); err != nil {
+					t.Fatalf("Decode(%q) failed: %v", data, err)
+				}
+				if decoded["s"] != test.expected {
+					t.Errorf("Decode(%q) = %q, expected %q", data, decoded["s"], test.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestEdgeCases_ValueEndingAtEOF(t *testing.T) {
+	inputs := []string{
+		`{}`,
+		`[]`,
+		`{"a":{}}`,
+		`[1,2,3]`,
+		`{"s":"str"}`,
+		`[-1.5e3]`,
+		`{"n":42}`,
+		`[true]`,
+		`{"f":false}`,
+		`[null]`,
+	}
+
+	for _, input := range inputs {
+		for _, data := range []string{input, "garbage " + input} {
+			var unmarshaled interface{}
+			if err := Unmarshal([]byte(data), &unmarshaled); err != nil {
+				t.Errorf("Unmarshal(%q) failed: %v", data, err)
+			}
+
+			decoder := New(strings.NewReader(data))
+			var decoded interface{}
+			if err := decoder.Decode(&decoded); err != nil {
+				t.Errorf("Decode(%q) failed: %v", data, err)
+			}
+			if err := decoder.Decode(&decoded); err != io.EOF {
+				t.Errorf("Second Decode(%q) = %v, expected io.EOF", data, err)
+			}
+		}
+	}
+}
+
+func TestEdgeCases_TruncatedAtEOF(t *testing.T) {
+	inputs := []string{
+		`[1`,
+		`[1,`,
+		`{"a"`,
+		`{"a":`,
+		`{"a":"str`,
+		`{"a":-1.5e`,
+		`[tru`,
+		`[nul`,
+	}
+
+	for _, input := range inputs {
+		var unmarshaled interface{}
+		if err := Unmarshal([]byte(input), &unmarshaled); err == nil {
+			t.Errorf("Unmarshal(%q) succeeded, expected error", input)
+		}
+
+		// A truncated value is an error, distinct from the clean end of the stream
+		decoder := New(strings.NewReader(input))
+		var decoded interface{}
+		err := decoder.Decode(&decoded)
+		if jsonErr, ok := err.(*Error); !ok || jsonErr.Type != ErrEOF {
+			t.Errorf("Decode(%q) = %v, expected EOF error", input, err)
+		}
+		if err := decoder.Decode(&decoded); err != io.EOF {
+			t.Errorf("Second Decode(%q) = %v, expected io.EOF", input, err)
+		}
+	}
+}
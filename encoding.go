@@ -0,0 +1,173 @@
+package jsonex
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// wideEncoding is UTF-16 or UTF-32 in either byte order
+type wideEncoding struct {
+	unitSize  int // bytes per code unit, 2 or 4
+	bigEndian bool
+}
+
+// detectWideEncoding returns the encoding announced by a UTF-16 or UTF-32 byte order
+// mark at the start of data and the length of the mark. ok is false for any other data
+func detectWideEncoding(data []byte) (enc wideEncoding, bomLen int, ok bool) {
+	switch {
+	// UTF-32LE must be checked before UTF-16LE, whose mark is a prefix of it
+	case len(data) >= 4 && data[0] == 0xFF && data[1] == 0xFE && data[2] == 0 && data[3] == 0:
+		return wideEncoding{unitSize: 4}, 4, true
+	case len(data) >= 4 && data[0] == 0 && data[1] == 0 && data[2] == 0xFE && data[3] == 0xFF:
+		return wideEncoding{unitSize: 4, bigEndian: true}, 4, true
+	case len(data) >= 2 && data[0] == 0xFF && data[1] == 0xFE:
+		return wideEncoding{unitSize: 2}, 2, true
+	case len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF:
+		return wideEncoding{unitSize: 2, bigEndian: true}, 2, true
+	}
+	return wideEncoding{}, 0, false
+}
+
+// transcodeToUTF8 converts UTF-16 or UTF-32 data starting with a byte order mark to
+// UTF-8 without the mark. Other data is returned as is
+func transcodeToUTF8(data []byte) []byte {
+	enc, bomLen, ok := detectWideEncoding(data)
+	if !ok {
+		return data
+	}
+
+	d := &wideDecoder{enc: enc}
+	result, n := d.decode(make([]byte, 0, len(data)), data[bomLen:])
+	return d.flush(result, data[bomLen+n:])
+}
+
+// wideDecoder converts a UTF-16 or UTF-32 stream to UTF-8. Invalid code units,
+// such as unpaired surrogates, become U+FFFD
+type wideDecoder struct {
+	enc     wideEncoding
+	high    rune // pending high surrogate of UTF-16
+	hasHigh bool
+}
+
+// unit reads the code unit at the start of data
+func (d *wideDecoder) unit(data []byte) rune {
+	if d.enc.unitSize == 2 {
+		if d.enc.bigEndian {
+			return rune(data[0])<<8 | rune(data[1])
+		}
+		return rune(data[1])<<8 | rune(data[0])
+	}
+	if d.enc.bigEndian {
+		return rune(data[0])<<24 | rune(data[1])<<16 | rune(data[2])<<8 | rune(data[3])
+	}
+	return rune(data[3])<<24 | rune(data[2])<<16 | rune(data[1])<<8 | rune(data[0])
+}
+
+// decode appends the UTF-8 encoding of the complete code units in data to dst and
+// returns the number of bytes of data consumed. A high surrogate at the end is kept
+// until the next call, so a surrogate pair may be split between calls
+func (d *wideDecoder) decode(dst, data []byte) ([]byte, int) {
+	n := 0
+	for ; n+d.enc.unitSize <= len(data); n += d.enc.unitSize {
+		r := d.unit(data[n:])
+		if d.enc.unitSize == 4 {
+			dst = utf8.AppendRune(dst, r)
+			continue
+		}
+
+		if d.hasHigh {
+			d.hasHigh = false
+			if isLowSurrogate(r) {
+				dst = utf8.AppendRune(dst, decodeSurrogatePair(d.high, r))
+				continue
+			}
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		}
+		if isHighSurrogate(r) {
+			d.high, d.hasHigh = r, true
+			continue
+		}
+		// utf8.AppendRune replaces an unpaired low surrogate with U+FFFD
+		dst = utf8.AppendRune(dst, r)
+	}
+	return dst, n
+}
+
+// flush appends U+FFFD for a pending high surrogate and for an incomplete code unit
+// left at the end of the input
+func (d *wideDecoder) flush(dst, rest []byte) []byte {
+	if d.hasHigh {
+		d.hasHigh = false
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+	}
+	if len(rest) > 0 {
+		dst = utf8.AppendRune(dst, utf8.RuneError)
+	}
+	return dst
+}
+
+// encodingReader transcodes input starting with a UTF-16 or UTF-32 byte order mark
+// to UTF-8 while reading. Other input is passed through unchanged
+type encodingReader struct {
+	reader   io.Reader
+	detected bool
+	decoder  *wideDecoder // nil for input without a UTF-16 or UTF-32 mark
+	chunk    []byte       // read buffer
+	in       []byte       // bytes read but not decoded yet
+	out      []byte       // decoded bytes not returned yet
+	err      error        // error of the underlying reader, returned once out is drained
+}
+
+// newEncodingReader creates a reader that detects the encoding of reader by its byte order mark
+func newEncodingReader(reader io.Reader) *encodingReader {
+	return &encodingReader{reader: reader}
+}
+
+func (r *encodingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.detected && r.decoder == nil {
+			return r.reader.Read(p)
+		}
+		r.fill()
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill reads from the underlying reader and decodes what it can
+func (r *encodingReader) fill() {
+	if r.chunk == nil {
+		r.chunk = make([]byte, 4096)
+	}
+	n, err := r.reader.Read(r.chunk)
+	r.in = append(r.in, r.chunk[:n]...)
+	r.err = err
+
+	if !r.detected {
+		// The longest byte order mark has 4 bytes
+		if len(r.in) < 4 && err == nil {
+			return
+		}
+		r.detected = true
+		enc, bomLen, ok := detectWideEncoding(r.in)
+		if !ok {
+			r.out, r.in = r.in, nil
+			return
+		}
+		r.decoder = &wideDecoder{enc: enc}
+		r.in = r.in[bomLen:]
+	}
+
+	var used int
+	r.out, used = r.decoder.decode(r.out[:0], r.in)
+	r.in = append(r.in[:0], r.in[used:]...)
+	if err == io.EOF {
+		r.out = r.decoder.flush(r.out, r.in)
+		r.in = nil
+	}
+}
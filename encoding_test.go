@@ -0,0 +1,135 @@
+package jsonex
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// encodeWide encodes s as UTF-16 or UTF-32 preceded by a byte order mark
+func encodeWide(s string, unitSize int, order binary.AppendByteOrder) []byte {
+	var units []uint32
+	if unitSize == 2 {
+		for _, u := range utf16.Encode([]rune("\uFEFF" + s)) {
+			units = append(units, uint32(u))
+		}
+	} else {
+		for _, r := range "\uFEFF" + s {
+			units = append(units, uint32(r))
+		}
+	}
+
+	var data []byte
+	for _, u := range units {
+		if unitSize == 2 {
+			data = order.AppendUint16(data, uint16(u))
+		} else {
+			data = order.AppendUint32(data, u)
+		}
+	}
+	return data
+}
+
+func TestTranscodeToUTF8(t *testing.T) {
+	const text = `log {"name": "café 😀"} end`
+	tests := []struct {
+		name     string
+		unitSize int
+		order    binary.AppendByteOrder
+	}{
+		{"UTF-16LE", 2, binary.LittleEndian},
+		{"UTF-16BE", 2, binary.BigEndian},
+		{"UTF-32LE", 4, binary.LittleEndian},
+		{"UTF-32BE", 4, binary.BigEndian},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := encodeWide(text, test.unitSize, test.order)
+			if result := string(transcodeToUTF8(data)); result != text {
+				t.Errorf("transcodeToUTF8 = %q, expected %q", result, text)
+			}
+
+			// One byte at a time splits code units and the surrogate pair between reads
+			result, err := io.ReadAll(newEncodingReader(iotest.OneByteReader(strings.NewReader(string(data)))))
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if string(result) != text {
+				t.Errorf("encodingReader = %q, expected %q", result, text)
+			}
+		})
+	}
+}
+
+func TestTranscodeToUTF8_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"No byte order mark", []byte(`{"a":1}`), `{"a":1}`},
+		{"UTF-8 byte order mark is kept", []byte("\xEF\xBB\xBF{}"), "\xEF\xBB\xBF{}"},
+		{"Unpaired high surrogate", []byte{0xFF, 0xFE, 0x3D, 0xD8, 'a', 0}, "\uFFFDa"},
+		{"Unpaired low surrogate", []byte{0xFF, 0xFE, 0x00, 0xDC, 'a', 0}, "\uFFFDa"},
+		{"High surrogate at end", []byte{0xFF, 0xFE, 'a', 0, 0x3D, 0xD8}, "a\uFFFD"},
+		{"Incomplete code unit", []byte{0xFF, 0xFE, 'a', 0, 'b'}, "a\uFFFD"},
+		{"Beyond U+10FFFF", []byte{0xFF, 0xFE, 0, 0, 0, 0, 0x11, 0}, "\uFFFD"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if result := string(transcodeToUTF8(test.data)); result != test.expected {
+				t.Errorf("transcodeToUTF8 = %q, expected %q", result, test.expected)
+			}
+			result, err := io.ReadAll(newEncodingReader(iotest.OneByteReader(strings.NewReader(string(test.data)))))
+			if err != nil || string(result) != test.expected {
+				t.Errorf("encodingReader = %q, %v, expected %q", result, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestWithAutoDetectEncoding(t *testing.T) {
+	data := encodeWide(`garbage {"name": "😀", "n": 1} trailing`, 2, binary.LittleEndian)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithAutoDetectEncoding()); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["name"] != "😀" || result["n"] != float64(1) {
+		t.Errorf("Unmarshal = %v", result)
+	}
+
+	// Without the option the NUL bytes between characters break the value
+	if err := Unmarshal(data, &result); err == nil {
+		t.Error("Unmarshal of UTF-16 without WithAutoDetectEncoding succeeded")
+	}
+
+	decoder := New(strings.NewReader(string(data)), WithAutoDetectEncoding(), WithBufferSize(7))
+	result = nil
+	if err := decoder.Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if result["name"] != "😀" || result["n"] != float64(1) {
+		t.Errorf("Decode = %v", result)
+	}
+	if err := decoder.Decode(&result); err != io.EOF {
+		t.Errorf("Decode at end = %v, expected io.EOF", err)
+	}
+
+	// Each reader of NewMulti has its own byte order mark
+	decoder = NewMulti([]io.Reader{
+		strings.NewReader(string(encodeWide(`{"a": 1}`, 2, binary.BigEndian))),
+		strings.NewReader(`{"b": 2}`),
+	}, WithAutoDetectEncoding())
+	for _, key := range []string{"a", "b"} {
+		result = nil
+		if err := decoder.Decode(&result); err != nil || result[key] == nil {
+			t.Errorf("Decode = %v, %v, expected key %s", result, err, key)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package jsonex
 
-import "fmt"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
 
 // ErrorType represents the type of error that occurred during parsing
 type ErrorType int
@@ -31,11 +37,49 @@ func (t ErrorType) String() string {
 	}
 }
 
+// Sentinel errors matched by errors.Is for an *Error of the corresponding ErrorType,
+// such as errors.Is(err, ErrSyntaxSentinel) for a syntax error
+var (
+	ErrSyntaxSentinel      = errors.New(ErrSyntax.String())
+	ErrUnicodeSentinel     = errors.New(ErrUnicode.String())
+	ErrEscapeSentinel      = errors.New(ErrEscape.String())
+	ErrEOFSentinel         = errors.New(ErrEOF.String())
+	ErrInvalidJSONSentinel = errors.New(ErrInvalidJSON.String())
+)
+
+// sentinel returns the sentinel error of the type, or nil for unknown types
+func (t ErrorType) sentinel() error {
+	switch t {
+	case ErrSyntax:
+		return ErrSyntaxSentinel
+	case ErrUnicode:
+		return ErrUnicodeSentinel
+	case ErrEscape:
+		return ErrEscapeSentinel
+	case ErrEOF:
+		return ErrEOFSentinel
+	case ErrInvalidJSON:
+		return ErrInvalidJSONSentinel
+	default:
+		return nil
+	}
+}
+
+// ErrNoJSON is matched by errors.Is for errors reporting that the input contains no
+// start of a JSON value at all, as opposed to input whose candidates all failed to parse
+var ErrNoJSON = errors.New("no JSON found")
+
+// ErrNoValidJSON is matched by errors.Is for errors reporting that no value could be
+// extracted from the input: errors matching ErrNoJSON, input whose candidates all failed
+// to parse and input without a value within the WithMaxGarbagePrefix limit
+var ErrNoValidJSON = errors.New("no valid JSON found")
+
 // Position represents a position in the input stream
+// Offset is an int64 so that positions in streams larger than 2GB are exact on 32-bit platforms
 type Position struct {
-	Offset int // byte offset
-	Line   int // line number (1-based)
-	Column int // column number (1-based)
+	Offset int64 // byte offset
+	Line   int   // line number (1-based)
+	Column int   // column number in characters (1-based)
 }
 
 // String returns the string representation of Position
@@ -49,6 +93,10 @@ type Error struct {
 	Message  string
 	Position Position
 	Context  string
+
+	noJSON  bool  // the input has no start of a JSON value
+	noValid bool  // no value could be extracted from the input
+	err     error // underlying error, such as a *json.UnmarshalTypeError
 }
 
 // Error implements the error interface
@@ -59,13 +107,108 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("%s at %s: %s", e.Type, e.Position, e.Message)
 }
 
+// Is reports whether target is the sentinel of the error's type, ErrNoJSON for input
+// without any JSON value or ErrNoValidJSON for input without any value extracted
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNoJSON:
+		return e.noJSON
+	case ErrNoValidJSON:
+		return e.noValid
+	}
+	return target != nil && target == e.Type.sentinel()
+}
+
+// Unwrap returns the underlying error, such as the *json.UnmarshalTypeError of a value
+// that does not fit the destination, or nil
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Pretty renders the error followed by the offending line of source and a caret under
+// the error position, for display in command line tools. source must be the input the
+// error was reported for
+func (e *Error) Pretty(source []byte) string {
+	if e.Position.Offset < 0 || e.Position.Offset > int64(len(source)) || e.Position.Line == 0 {
+		return e.Error()
+	}
+	offset := int(e.Position.Offset)
+
+	start := bytes.LastIndexByte(source[:offset], '\n') + 1
+	end := len(source)
+	if i := bytes.IndexByte(source[offset:], '\n'); i >= 0 {
+		end = offset + i
+	}
+	line := bytes.TrimSuffix(source[start:end], []byte{'\r'})
+
+	// Keep tabs so that the caret lines up with the rendered line
+	var caret strings.Builder
+	for _, r := range string(source[start:offset]) {
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+
+	// The source line replaces the context snippet
+	header := *e
+	header.Context = ""
+
+	number := fmt.Sprint(e.Position.Line)
+	margin := strings.Repeat(" ", len(number))
+	return fmt.Sprintf("%s\n%s | %s\n%s | %s", header.Error(), number, line, margin, caret.String())
+}
+
 // position represents internal position tracking (unexported)
 type position struct {
-	offset int
+	offset int64
 	line   int
 	column int
 }
 
+// advance returns the position after consuming b. Only the first byte of a UTF-8
+// character advances the column, so that columns count characters
+func (p position) advance(b byte) position {
+	p.offset++
+	if b == '\n' {
+		p.line++
+		p.column = 1
+	} else if utf8.RuneStart(b) {
+		p.column++
+	}
+	return p
+}
+
+// columnsIn counts the bytes of data that advance the column, which are those
+// starting a UTF-8 character
+func columnsIn(data []byte) int {
+	n := 0
+	for _, b := range data {
+		if utf8.RuneStart(b) {
+			n++
+		}
+	}
+	return n
+}
+
+// advanceAll moves the position past data
+func (p position) advanceAll(data []byte) position {
+	for _, b := range data {
+		p = p.advance(b)
+	}
+	return p
+}
+
+// quoteByte formats b for an error message, such as '}' or '\x00'
+func quoteByte(b byte) string {
+	if b >= 0x20 && b < 0x7f {
+		return "'" + string(b) + "'"
+	}
+	return fmt.Sprintf("'\\x%02x'", b)
+}
+
 // toPublic converts internal position to public Position
 func (p position) toPublic() Position {
 	return Position{
@@ -112,3 +255,19 @@ func newEOFError(pos position, message string, context ...string) *Error {
 func newInvalidJSONError(pos position, message string, context ...string) *Error {
 	return newError(ErrInvalidJSON, pos, message, context...)
 }
+
+// newNoValidJSONError creates an invalid JSON error for input from which no value could
+// be extracted, which matches ErrNoValidJSON
+func newNoValidJSONError(pos position, message string) *Error {
+	err := newInvalidJSONError(pos, message)
+	err.noValid = true
+	return err
+}
+
+// newNoJSONError creates an invalid JSON error for input without any start of a JSON
+// value, which matches ErrNoJSON and ErrNoValidJSON
+func newNoJSONError(pos position, message string) *Error {
+	err := newNoValidJSONError(pos, message)
+	err.noJSON = true
+	return err
+}
@@ -1,6 +1,11 @@
 package jsonex
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +86,175 @@ func TestNewErrorWithContext(t *testing.T) {
 		t.Errorf("newError Context = %s, expected %s", err.Context, "test context")
 	}
 }
+
+func TestError_Pretty(t *testing.T) {
+	source := "{\n  \"name\": \"test\",\n\t\"value\": @\n}\n"
+
+	var result interface{}
+	err := New(strings.NewReader(source)).Decode(&result)
+	jsonErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+
+	expected := "syntax error at line 3, column 11 (offset 30): unexpected character '@'\n" +
+		"3 | \t\"value\": @\n" +
+		"  | \t         ^"
+	if pretty := jsonErr.Pretty([]byte(source)); pretty != expected {
+		t.Errorf("Pretty() =\n%s\nexpected\n%s", pretty, expected)
+	}
+}
+
+func TestError_PrettyEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *Error
+		source   string
+		expected string
+	}{
+		{
+			name:     "Error at end of source",
+			err:      &Error{Type: ErrEOF, Message: "unexpected end", Position: Position{Offset: 5, Line: 1, Column: 6}},
+			source:   `{"a":`,
+			expected: "unexpected end of file at line 1, column 6 (offset 5): unexpected end\n1 | {\"a\":\n  |      ^",
+		},
+		{
+			name:     "CRLF line endings",
+			err:      &Error{Type: ErrSyntax, Message: "bad", Position: Position{Offset: 14, Line: 10, Column: 3}},
+			source:   "x\r\nx\r\nx\r\nx\r\nab@\r\n",
+			expected: "syntax error at line 10, column 3 (offset 14): bad\n10 | ab@\n   |   ^",
+		},
+		{
+			name:     "Offset outside source",
+			err:      &Error{Type: ErrSyntax, Message: "bad", Position: Position{Offset: 100, Line: 1, Column: 101}},
+			source:   `{}`,
+			expected: "syntax error at line 1, column 101 (offset 100): bad",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if pretty := test.err.Pretty([]byte(test.source)); pretty != test.expected {
+				t.Errorf("Pretty() =\n%q\nexpected\n%q", pretty, test.expected)
+			}
+		})
+	}
+}
+
+func TestError_Context(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+		context string
+	}{
+		{`{"a": 1,}`, `expected '"', found '}'`, `{"a": 1,}`},
+		{`[1, 2 3]`, `expected ',' or ']', found '3'`, `[1, 2 3]`},
+		{`{"k" 1}`, `expected ':', found '1'`, `{"k" 1}`},
+		{"[\x01]", `unexpected character '\x01'`, "[ ]"},
+		{`{"description": "a long value", "count": ?, "tail": "more text here"}`,
+			`unexpected character '?'`, `...alue", "count": ?, "tail": "more...`},
+	}
+
+	for _, test := range tests {
+		var result interface{}
+		err := New(strings.NewReader(test.input)).Decode(&result)
+		jsonErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Decode(%s) error = %v, expected *Error", test.input, err)
+			continue
+		}
+		if jsonErr.Message != test.message {
+			t.Errorf("Decode(%s) message = %s, expected %s", test.input, jsonErr.Message, test.message)
+		}
+		if jsonErr.Context != test.context {
+			t.Errorf("Decode(%s) context = %s, expected %s", test.input, jsonErr.Context, test.context)
+		}
+	}
+}
+
+func TestErrNoJSON(t *testing.T) {
+	var v interface{}
+	tests := []struct {
+		name   string
+		call   func() error
+		noJSON bool
+	}{
+		{"Plain text", func() error { return Unmarshal([]byte("no json here"), &v) }, true},
+		{"Empty input", func() error { return Unmarshal(nil, &v) }, true},
+		{"Malformed JSON", func() error { return Unmarshal([]byte(`log {"a": }`), &v) }, false},
+		{"Scalars without candidates", func() error { return Unmarshal([]byte("abc"), &v, WithAllowScalars(true)) }, true},
+		{"First match", func() error { return Unmarshal([]byte("text"), &v, WithFirstMatch()) }, true},
+		{"First match malformed", func() error { return Unmarshal([]byte("[1,"), &v, WithFirstMatch()) }, false},
+		{"UnmarshalAll", func() error { _, err := UnmarshalAll([]byte("text")); return err }, true},
+		{"Strict boundaries", func() error { return Unmarshal([]byte("  "), &v, WithStrictBoundaries()) }, true},
+		{"Windowed", func() error {
+			return UnmarshalReader(bytes.NewReader([]byte("[1, } "+strings.Repeat("text ", 20))), &v, WithLargeFileMode(16))
+		}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			var jsonErr *Error
+			if !errors.As(err, &jsonErr) || jsonErr.Type != ErrInvalidJSON {
+				t.Fatalf("error = %v, expected ErrInvalidJSON", err)
+			}
+			if errors.Is(err, ErrNoJSON) != test.noJSON {
+				t.Errorf("errors.Is(%v, ErrNoJSON) = %v, expected %v", err, !test.noJSON, test.noJSON)
+			}
+		})
+	}
+}
+
+func TestErrNoValidJSON(t *testing.T) {
+	var v interface{}
+	tests := []struct {
+		name    string
+		err     error
+		noValid bool
+	}{
+		{"Plain text", Unmarshal([]byte("no json here"), &v), true},
+		{"Malformed JSON", Unmarshal([]byte(`log {"a": }`), &v), true},
+		{"Garbage prefix", Unmarshal([]byte(`log line {"a": 1}`), &v, WithMaxGarbagePrefix(4)), true},
+		{"UnmarshalAll", func() error { _, err := UnmarshalAll([]byte("[1,")); return err }(), true},
+		{"Decode error", wrapDecodeError(json.Unmarshal([]byte(`"a"`), new(int))), false},
+		{"Syntax error", newSyntaxError(position{}, "unexpected character"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if errors.Is(test.err, ErrNoValidJSON) != test.noValid {
+				t.Errorf("errors.Is(%v, ErrNoValidJSON) = %v, expected %v", test.err, !test.noValid, test.noValid)
+			}
+		})
+	}
+}
+
+func TestError_IsSentinel(t *testing.T) {
+	sentinels := map[ErrorType]error{
+		ErrSyntax:      ErrSyntaxSentinel,
+		ErrUnicode:     ErrUnicodeSentinel,
+		ErrEscape:      ErrEscapeSentinel,
+		ErrEOF:         ErrEOFSentinel,
+		ErrInvalidJSON: ErrInvalidJSONSentinel,
+	}
+
+	for errType := range sentinels {
+		err := fmt.Errorf("wrapped: %w", newError(errType, position{}, "test"))
+		for other, sentinel := range sentinels {
+			if errors.Is(err, sentinel) != (other == errType) {
+				t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, !(other == errType))
+			}
+		}
+	}
+
+	// Errors returned by the package match their sentinel
+	var v interface{}
+	err := New(strings.NewReader(`[1, 2`)).Decode(&v)
+	if !errors.Is(err, ErrEOFSentinel) || errors.Is(err, ErrSyntaxSentinel) {
+		t.Errorf("Decode of a truncated array = %v, expected to match ErrEOFSentinel only", err)
+	}
+	if errors.Is(newError(ErrorType(99), position{}, "test"), nil) {
+		t.Error("An unknown error type matched nil")
+	}
+}
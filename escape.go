@@ -2,7 +2,6 @@ package jsonex
 
 import (
 	"strconv"
-	"strings"
 )
 
 // processEscape processes escape sequences in JSON strings
@@ -28,7 +27,7 @@ func processEscape(data []byte) ([]byte, error) {
 
 		// Handle escape sequence
 		if pos+1 >= len(data) {
-			return nil, newEscapeError(position{offset: pos}, "incomplete escape sequence")
+			return nil, newEscapeError(positionIn(data, pos), "incomplete escape sequence")
 		}
 
 		switch data[pos+1] {
@@ -57,31 +56,31 @@ func processEscape(data []byte) ([]byte, error) {
 			result = append(result, '\t')
 			pos += 2
 		case 'u':
-			// Unicode escape sequence
-			if pos+5 >= len(data) {
-				return nil, newEscapeError(position{offset: pos}, "incomplete unicode escape sequence")
+			// Unicode escape sequence needs data[pos:pos+6]
+			if pos+6 > len(data) {
+				return nil, newEscapeError(positionIn(data, pos), "incomplete unicode escape sequence")
 			}
 
 			hexStr := string(data[pos+2 : pos+6])
 			r, err := decodeUnicodeEscape(hexStr)
 			if err != nil {
-				return nil, newEscapeError(position{offset: pos}, "invalid unicode escape sequence: "+hexStr)
+				return nil, newEscapeError(positionIn(data, pos), "invalid unicode escape sequence: "+hexStr)
 			}
 
-			// Check for surrogate pairs
+			// Check for surrogate pairs, which need data[pos:pos+12]
 			if isHighSurrogate(r) {
-				if pos+11 >= len(data) || data[pos+6] != '\\' || data[pos+7] != 'u' {
-					return nil, newEscapeError(position{offset: pos}, "incomplete surrogate pair")
+				if pos+12 > len(data) || data[pos+6] != '\\' || data[pos+7] != 'u' {
+					return nil, newEscapeError(positionIn(data, pos), "incomplete surrogate pair")
 				}
 
 				lowHexStr := string(data[pos+8 : pos+12])
 				lowR, err := decodeUnicodeEscape(lowHexStr)
 				if err != nil {
-					return nil, newEscapeError(position{offset: pos}, "invalid low surrogate: "+lowHexStr)
+					return nil, newEscapeError(positionIn(data, pos), "invalid low surrogate: "+lowHexStr)
 				}
 
 				if !isLowSurrogate(lowR) {
-					return nil, newEscapeError(position{offset: pos}, "invalid surrogate pair")
+					return nil, newEscapeError(positionIn(data, pos), "invalid surrogate pair")
 				}
 
 				// Decode surrogate pair
@@ -90,7 +89,7 @@ func processEscape(data []byte) ([]byte, error) {
 				result = append(result, utf8Bytes...)
 				pos += 12
 			} else if isLowSurrogate(r) {
-				return nil, newEscapeError(position{offset: pos}, "unexpected low surrogate")
+				return nil, newEscapeError(positionIn(data, pos), "unexpected low surrogate")
 			} else {
 				// Regular Unicode escape
 				utf8Bytes := encodeUTF8Rune(r)
@@ -98,13 +97,29 @@ func processEscape(data []byte) ([]byte, error) {
 				pos += 6
 			}
 		default:
-			return nil, newEscapeError(position{offset: pos}, "invalid escape character: \\"+string(data[pos+1]))
+			return nil, newEscapeError(positionIn(data, pos), "invalid escape character: \\"+string(data[pos+1]))
 		}
 	}
 
 	return result, nil
 }
 
+// escapeErrorAt moves an error of processEscape to pos, the opening quote of the string
+// in the input. The parser re-emits strings, so offsets within the processed contents
+// cannot be mapped back to the input exactly
+func escapeErrorAt(err error, pos position) error {
+	if jsonErr, ok := err.(*Error); ok {
+		jsonErr.Position = pos.toPublic()
+	}
+	return err
+}
+
+// positionIn returns the offset, line and column of data[pos]
+func positionIn(data []byte, pos int) position {
+	pos = min(pos, len(data))
+	return position{line: 1, column: 1}.advanceAll(data[:pos])
+}
+
 // decodeUnicodeEscape decodes a 4-character hex string to a rune
 func decodeUnicodeEscape(hex string) (rune, error) {
 	if len(hex) != 4 {
@@ -136,6 +151,15 @@ func hasEscapeSequences(data []byte) bool {
 	return false
 }
 
+// isEscapeChar checks if b may follow a backslash in a JSON string
+func isEscapeChar(b byte) bool {
+	switch b {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't', 'u':
+		return true
+	}
+	return false
+}
+
 // isHexDigit checks if a byte is a valid hexadecimal digit
 func isHexDigit(b byte) bool {
 	return (b >= '0' && b <= '9') ||
@@ -143,6 +167,9 @@ func isHexDigit(b byte) bool {
 		(b >= 'a' && b <= 'f')
 }
 
+// hexDigits are the digits of unicode escapes written by encodeEscape, in lower case like encoding/json
+const hexDigits = "0123456789abcdef"
+
 // encodeEscape encodes special characters as escape sequences
 func encodeEscape(data []byte) []byte {
 	result := make([]byte, 0, len(data)*2) // Worst case: every byte needs escaping
@@ -165,8 +192,8 @@ func encodeEscape(data []byte) []byte {
 			result = append(result, '\\', 't')
 		default:
 			if b < 0x20 {
-				// Control characters need unicode escape
-				result = append(result, []byte("\\u"+strings.ToUpper(strconv.FormatUint(uint64(b), 16)))...)
+				// Control characters need a unicode escape of exactly four hex digits
+				result = append(result, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF])
 			} else {
 				result = append(result, b)
 			}
@@ -179,11 +206,11 @@ func encodeEscape(data []byte) []byte {
 // validateEscapeSequence validates an escape sequence starting at the given position
 func validateEscapeSequence(data []byte, pos int) error {
 	if pos >= len(data) || data[pos] != '\\' {
-		return newEscapeError(position{offset: pos}, "not an escape sequence")
+		return newEscapeError(positionIn(data, pos), "not an escape sequence")
 	}
 
 	if pos+1 >= len(data) {
-		return newEscapeError(position{offset: pos}, "incomplete escape sequence")
+		return newEscapeError(positionIn(data, pos), "incomplete escape sequence")
 	}
 
 	switch data[pos+1] {
@@ -191,17 +218,17 @@ func validateEscapeSequence(data []byte, pos int) error {
 		return nil // Valid simple escape
 	case 'u':
 		if pos+5 >= len(data) {
-			return newEscapeError(position{offset: pos}, "incomplete unicode escape")
+			return newEscapeError(positionIn(data, pos), "incomplete unicode escape")
 		}
 		// Validate hex digits
 		for i := pos + 2; i < pos+6; i++ {
 			if !isHexDigit(data[i]) {
-				return newEscapeError(position{offset: pos}, "invalid hex digit in unicode escape")
+				return newEscapeError(positionIn(data, pos), "invalid hex digit in unicode escape")
 			}
 		}
 		return nil
 	default:
-		return newEscapeError(position{offset: pos}, "invalid escape character")
+		return newEscapeError(positionIn(data, pos), "invalid escape character")
 	}
 }
 
@@ -2,6 +2,7 @@ package jsonex
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 )
 
@@ -138,7 +139,11 @@ func TestEncodeEscape(t *testing.T) {
 		{[]byte("back\\slash"), []byte("back\\\\slash")},
 		{[]byte("new\nline"), []byte("new\\nline")},
 		{[]byte("tab\there"), []byte("tab\\there")},
-		{[]byte("\x01"), []byte("\\u1")}, // Control character
+		{[]byte("\x01"), []byte("\\u0001")}, // Control character
+		{[]byte("\x00"), []byte("\\u0000")},
+		{[]byte("\x0A"), []byte("\\n")},
+		{[]byte("\x1F"), []byte("\\u001f")},
+		{[]byte("\x7F"), []byte("\x7F")}, // DEL needs no escape
 	}
 
 	for _, test := range tests {
@@ -147,6 +152,15 @@ func TestEncodeEscape(t *testing.T) {
 			t.Errorf("encodeEscape(%s) = %s, expected %s", test.input, result, test.expected)
 		}
 	}
+
+	// Every byte is escaped into a valid JSON string that decodes back to it
+	for b := 0; b < 0x80; b++ {
+		encoded := `"` + string(encodeEscape([]byte{byte(b)})) + `"`
+		var decoded string
+		if err := json.Unmarshal([]byte(encoded), &decoded); err != nil || decoded != string(rune(b)) {
+			t.Errorf("encodeEscape(%#x) = %s, which decodes to %q, %v", b, encoded, decoded, err)
+		}
+	}
 }
 
 func TestValidateEscapeSequence(t *testing.T) {
@@ -178,6 +192,35 @@ func TestValidateEscapeSequence(t *testing.T) {
 	}
 }
 
+func TestEscapeErrorPosition(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected Position
+	}{
+		{[]byte(`\x`), Position{Offset: 0, Line: 1, Column: 1}},
+		{[]byte(`abc\x`), Position{Offset: 3, Line: 1, Column: 4}},
+		{[]byte("a\nbc\\u12"), Position{Offset: 4, Line: 2, Column: 3}},
+		{[]byte("\n\n\\udc00"), Position{Offset: 2, Line: 3, Column: 1}},
+	}
+
+	for _, test := range tests {
+		_, err := processEscape(test.input)
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrEscape {
+			t.Errorf("processEscape(%q) error = %v, expected escape error", test.input, err)
+			continue
+		}
+		if jsonErr.Position != test.expected {
+			t.Errorf("processEscape(%q) position = %v, expected %v", test.input, jsonErr.Position, test.expected)
+		}
+	}
+
+	err := validateEscapeSequence([]byte("a\nb\\q"), 3)
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Position != (Position{Offset: 3, Line: 2, Column: 2}) {
+		t.Errorf("validateEscapeSequence position = %v, expected line 2, column 2", err)
+	}
+}
+
 func TestCountEscapeSequences(t *testing.T) {
 	tests := []struct {
 		input    []byte
@@ -198,3 +241,44 @@ func TestCountEscapeSequences(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessEscape_SurrogateBounds(t *testing.T) {
+	tests := []struct {
+		input    []byte
+		expected []byte
+		hasError bool
+	}{
+		// Surrogate pair ending exactly at the end of the data
+		{[]byte(`\uD83D\uDE00`), []byte("😀"), false},
+		{[]byte(`smile \uD83D\uDE00`), []byte("smile 😀"), false},
+		{[]byte(`\u0041`), []byte("A"), false},
+
+		// Truncated pairs must fail without panicking
+		{[]byte(`\uD83D`), nil, true},
+		{[]byte(`\uD83D\`), nil, true},
+		{[]byte(`\uD83D\u`), nil, true},
+		{[]byte(`\uD83D\uDE`), nil, true},
+		{[]byte(`\uD83D\uDE0`), nil, true},
+		{[]byte(`\uD83Dx\uDE00`), nil, true},
+		{[]byte(`\u004`), nil, true},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("processEscape(%s) panicked: %v", test.input, r)
+				}
+			}()
+
+			result, err := processEscape(test.input)
+			if (err != nil) != test.hasError {
+				t.Errorf("processEscape(%s) error = %v, expected error = %v", test.input, err, test.hasError)
+				return
+			}
+			if !test.hasError && !bytes.Equal(result, test.expected) {
+				t.Errorf("processEscape(%s) = %q, expected %q", test.input, result, test.expected)
+			}
+		}()
+	}
+}
@@ -1,7 +1,11 @@
 package jsonex
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -162,8 +166,124 @@ func FuzzDeepNesting(f *testing.F) {
 	})
 }
 
+// FuzzToken checks that Decoder.Token returns the tokens of encoding/json for valid input,
+// and that a fully consumed token sequence rebuilds the values returned by Decode
+func FuzzToken(f *testing.F) {
+	f.Add([]byte(`{"key": "value", "list": [1, 2.5, true, null, {}]}`))
+	f.Add([]byte(`[[], {"a": [{"b": "\u00e9"}]}]`))
+	f.Add([]byte(`garbage {"valid": 1} noise [2] end`))
+	f.Add([]byte(`{"a": {"b": [[{}], [1, {"c": null}]]}} [[[]]]`))
+	f.Add([]byte(`{"incomplete": [1, 2`))
+	f.Add([]byte(`{"a" 1} [1,, 2] {"b": [}] {"c": 3}`))
+	f.Add([]byte(`[1, 2]] {"a": 1}}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("Token panicked with input %q: %v", data, r)
+			}
+		}()
+
+		var tokens []Token
+		decoder := New(strings.NewReader(string(data)))
+		var err error
+		for i := 0; i < 1000; i++ { // Limit iterations to prevent infinite loops
+			var token Token
+			if token, err = decoder.Token(); err != nil {
+				break
+			}
+			tokens = append(tokens, token)
+		}
+		if err != io.EOF && !isAcceptableError(err) {
+			t.Errorf("Unexpected error for input %q: %v", data, err)
+		}
+
+		// A token sequence read to the end without error holds the values that Decode returns
+		if err == io.EOF {
+			var expected []interface{}
+			decoder := New(strings.NewReader(string(data)))
+			for {
+				var value interface{}
+				decodeErr := decoder.Decode(&value)
+				if decodeErr == io.EOF {
+					break
+				}
+				if decodeErr != nil {
+					t.Fatalf("Decode(%q) failed after Token succeeded: %v", data, decodeErr)
+				}
+				expected = append(expected, value)
+			}
+			if values := tokenValues(tokens); !reflect.DeepEqual(values, expected) {
+				t.Errorf("values of Token(%q) = %v, Decode returned %v", data, values, expected)
+			}
+		}
+
+		// Compare with encoding/json for a single valid object or array
+		trimmed := bytes.TrimSpace(data)
+		if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') || !json.Valid(data) || !utf8.Valid(data) {
+			return
+		}
+		var expected []Token
+		stdlib := json.NewDecoder(bytes.NewReader(data))
+		for {
+			token, stdErr := stdlib.Token()
+			if stdErr == io.EOF {
+				break
+			}
+			if stdErr != nil {
+				return // numbers out of the float64 range
+			}
+			expected = append(expected, token)
+		}
+
+		// Strict UTF-8 rejects escaped lone surrogates, which encoding/json replaces
+		var jsonErr *Error
+		if errors.As(err, &jsonErr) && (jsonErr.Type == ErrUnicode || jsonErr.Type == ErrEscape) {
+			return
+		}
+		if err != io.EOF || !reflect.DeepEqual(tokens, expected) {
+			t.Errorf("Token(%q) = %v, %v, expected %v", data, tokens, err, expected)
+		}
+	})
+}
+
 // Helper functions for fuzzing
 
+// tokenValues rebuilds the top-level values of a complete token sequence
+func tokenValues(tokens []Token) []interface{} {
+	var values []interface{}
+	for len(tokens) > 0 {
+		var value interface{}
+		value, tokens = tokenValue(tokens)
+		values = append(values, value)
+	}
+	return values
+}
+
+// tokenValue rebuilds the value starting at the first token and returns the tokens after it
+func tokenValue(tokens []Token) (interface{}, []Token) {
+	switch tokens[0] {
+	case Delim('{'):
+		object := map[string]interface{}{}
+		tokens = tokens[1:]
+		for tokens[0] != Delim('}') {
+			key := tokens[0].(string)
+			object[key], tokens = tokenValue(tokens[1:])
+		}
+		return object, tokens[1:]
+	case Delim('['):
+		array := []interface{}{}
+		tokens = tokens[1:]
+		for tokens[0] != Delim(']') {
+			var element interface{}
+			element, tokens = tokenValue(tokens)
+			array = append(array, element)
+		}
+		return array, tokens[1:]
+	}
+	return tokens[0], tokens[1:]
+}
+
 // isAcceptableError checks if an error is expected/acceptable during fuzzing
 func isAcceptableError(err error) bool {
 	if err == nil {
@@ -0,0 +1,28 @@
+package jsonex
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Marshal returns the JSON encoding of v, which Unmarshal reads back into the same value
+// Strings are escaped like the rest of this package: quotes, backslashes and control
+// characters, as RFC 8259 requires, and U+2028 and U+2029 unless WithEscapeLineSeparators
+// is false. Characters such as '<' and '&' are kept as is: unlike json.Marshal, there is
+// no HTML escaping. Invalid UTF-8 is replaced with U+FFFD, as encoding/json does, and
+// written as the raw character. Of the options, only WithEscapeLineSeparators applies
+func Marshal(v interface{}, opts ...Option) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Encode terminates the value with a newline, and its strings are written again with
+	// the escapes of this package
+	options := applyOptions(opts...)
+	encoded := bytes.TrimSuffix(buf.Bytes(), []byte{'\n'})
+	n := &normalizer{data: encoded, rawSeparators: options.rawSeparators}
+	return n.value(make([]byte, 0, len(encoded)))
+}
@@ -0,0 +1,79 @@
+package jsonex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	type record struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Count int      `json:"count"`
+	}
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{map[string]interface{}{"a": 1}, `{"a":1}`},
+		{record{Name: "x", Tags: []string{"a"}, Count: 2}, `{"name":"x","tags":["a"],"count":2}`},
+		{"<tag> & more", `"<tag> & more"`},
+		{"\x00\x01\x1f\x7f", `"\u0000\u0001\u001f` + "\x7f" + `"`},
+		{"quote \" backslash \\ newline \n", `"quote \" backslash \\ newline \n"`},
+	}
+
+	for _, test := range tests {
+		result, err := Marshal(test.input)
+		if err != nil {
+			t.Fatalf("Marshal(%v) failed: %v", test.input, err)
+		}
+		if string(result) != test.expected {
+			t.Errorf("Marshal(%v) = %s, expected %s", test.input, result, test.expected)
+		}
+	}
+
+	// Strings are escaped exactly as encodeEscape does
+	var all []byte
+	for b := 0; b < 0x80; b++ {
+		all = append(all, byte(b))
+	}
+	result, err := Marshal(string(all))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if expected := `"` + string(encodeEscape(all)) + `"`; string(result) != expected {
+		t.Errorf("Marshal = %s, expected %s", result, expected)
+	}
+
+	// Unmarshal reads the output back
+	input := record{Name: "line\nbreak \x01", Tags: []string{"<b>"}, Count: 3}
+	data, err := Marshal(input)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded record
+	if err := Unmarshal(append([]byte("log: "), data...), &decoded); err != nil {
+		t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+	}
+	if !reflect.DeepEqual(decoded, input) {
+		t.Errorf("Unmarshal(Marshal(%v)) = %v", input, decoded)
+	}
+
+	// Invalid UTF-8 is replaced, and the replacement character is not escaped
+	if result, err := Marshal("a\xffb"); err != nil || string(result) != "\"a\uFFFDb\"" {
+		t.Errorf("Marshal = %s, %v, expected U+FFFD", result, err)
+	}
+
+	// The line and paragraph separators are escaped unless disabled
+	if result, err := Marshal(map[string]string{"\u2028": "a\u2029b"}); err != nil || string(result) != `{"\u2028":"a\u2029b"}` {
+		t.Errorf("Marshal = %s, %v, expected escaped separators", result, err)
+	}
+	if result, err := Marshal(map[string]string{"\u2028": "a\u2029b"}, WithEscapeLineSeparators(false)); err != nil || string(result) != "{\"\u2028\":\"a\u2029b\"}" {
+		t.Errorf("Marshal = %s, %v, expected raw separators", result, err)
+	}
+
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("Marshal of a channel succeeded, expected error")
+	}
+}
@@ -0,0 +1,434 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// materializer builds Go values from extracted JSON without using encoding/json (unexported)
+// The input is the output of parser, so it is known to be syntactically valid
+type materializer struct {
+	data    []byte
+	pos     int
+	opts    options
+	depth   int                                     // number of enclosing objects and arrays
+	path    []string                                // keys and indices of the current value, tracked by tracksPath
+	convert func(token string) (interface{}, error) // number conversion, nil for float64
+}
+
+// materialize converts extracted JSON into interface{}, map[string]interface{},
+// []interface{}, string, float64, bool and nil values. Objects and arrays below
+// the lazy depth are kept as json.RawMessage
+func materialize(data []byte, opts options) (interface{}, error) {
+	m := &materializer{data: data, opts: opts, convert: numberConverter(opts)}
+	value, err := m.value()
+	if err != nil {
+		return nil, err
+	}
+	m.skipWhitespace()
+	if m.pos < len(m.data) {
+		return nil, m.syntaxError("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// assignValue stores a materialized value into the value pointed to by v
+func assignValue(v interface{}, value interface{}) error {
+	switch dst := v.(type) {
+	case *interface{}:
+		*dst = value
+		return nil
+	case *map[string]interface{}:
+		if obj, ok := value.(map[string]interface{}); ok {
+			*dst = obj
+			return nil
+		}
+	case *[]interface{}:
+		if arr, ok := value.([]interface{}); ok {
+			*dst = arr
+			return nil
+		}
+	default:
+		return newInvalidJSONError(position{}, fmt.Sprintf("unsupported destination type %T without encoding/json", v))
+	}
+	return newInvalidJSONError(position{}, fmt.Sprintf("cannot assign %T to %T", value, v))
+}
+
+// value materializes any JSON value at the current position
+func (m *materializer) value() (interface{}, error) {
+	m.skipWhitespace()
+	if m.pos >= len(m.data) {
+		return nil, newEOFError(position{offset: int64(m.pos)}, "unexpected end of JSON")
+	}
+
+	switch c := m.data[m.pos]; {
+	case (c == '{' || c == '[') && m.opts.lazyDepth > 0 && m.depth >= m.opts.lazyDepth:
+		return m.raw()
+	case c == '{':
+		return m.object()
+	case c == '[':
+		return m.array()
+	case c == '"':
+		return m.scalar(m.string())
+	case c == 't':
+		return m.scalar(true, m.literal("true"))
+	case c == 'f':
+		return m.scalar(false, m.literal("false"))
+	case c == 'n':
+		return m.scalar(nil, m.literal("null"))
+	case c == '-' || (c >= '0' && c <= '9'):
+		return m.scalar(m.number())
+	default:
+		return nil, m.syntaxError("unexpected character")
+	}
+}
+
+// object materializes a JSON object
+func (m *materializer) object() (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	var collected map[string]bool // keys whose values were collected into a slice
+
+	m.pos++ // '{'
+	m.depth++
+	defer func() { m.depth-- }()
+
+	m.skipWhitespace()
+	if m.consume('}') {
+		return obj, nil
+	}
+
+	for {
+		m.skipWhitespace()
+		key, err := m.string()
+		if err != nil {
+			return nil, err
+		}
+
+		m.skipWhitespace()
+		if !m.consume(':') {
+			return nil, m.syntaxError("expected ':'")
+		}
+
+		m.pushPath(key)
+		value, err := m.value()
+		m.popPath()
+		if err != nil {
+			return nil, err
+		}
+		if m.opts.collectDupKeys {
+			collected = m.collect(obj, collected, key, value)
+		} else {
+			obj[key] = value
+		}
+
+		m.skipWhitespace()
+		if m.consume('}') {
+			return obj, nil
+		}
+		if !m.consume(',') {
+			return nil, m.syntaxError("expected ',' or '}'")
+		}
+	}
+}
+
+// collect stores value under key, collecting the values of repeated keys into a slice
+func (m *materializer) collect(obj map[string]interface{}, collected map[string]bool, key string, value interface{}) map[string]bool {
+	existing, ok := obj[key]
+	switch {
+	case !ok:
+		obj[key] = value
+	case collected[key]:
+		obj[key] = append(existing.([]interface{}), value)
+	default:
+		obj[key] = []interface{}{existing, value}
+		if collected == nil {
+			collected = make(map[string]bool)
+		}
+		collected[key] = true
+	}
+	return collected
+}
+
+// array materializes a JSON array
+func (m *materializer) array() ([]interface{}, error) {
+	arr := make([]interface{}, 0)
+	m.pos++ // '['
+	m.depth++
+	defer func() { m.depth-- }()
+
+	m.skipWhitespace()
+	if m.consume(']') {
+		return arr, nil
+	}
+
+	for {
+		m.pushPath(strconv.Itoa(len(arr)))
+		value, err := m.value()
+		m.popPath()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+
+		m.skipWhitespace()
+		if m.consume(']') {
+			return arr, nil
+		}
+		if !m.consume(',') {
+			return nil, m.syntaxError("expected ',' or ']'")
+		}
+	}
+}
+
+// scalar passes a materialized string, number, boolean or null to the value hook
+func (m *materializer) scalar(value interface{}, err error) (interface{}, error) {
+	if err != nil || m.opts.valueHook == nil {
+		return value, err
+	}
+	// Limit the capacity so that the hook cannot overwrite the path by appending
+	return m.opts.valueHook(m.path[:len(m.path):len(m.path)], value), nil
+}
+
+// raw copies the object or array at the current position without decoding it
+func (m *materializer) raw() (json.RawMessage, error) {
+	start := m.pos
+	if err := m.skipContainer(); err != nil {
+		return nil, err
+	}
+	raw := make(json.RawMessage, m.pos-start)
+	copy(raw, m.data[start:m.pos])
+	return raw, nil
+}
+
+// skipContainer advances past the object or array at the current position
+func (m *materializer) skipContainer() error {
+	nesting := 0
+	inString := false
+	for ; m.pos < len(m.data); m.pos++ {
+		c := m.data[m.pos]
+		if inString {
+			switch c {
+			case '\\':
+				m.pos++
+			case '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			nesting++
+		case '}', ']':
+			nesting--
+			if nesting == 0 {
+				m.pos++
+				return nil
+			}
+		}
+	}
+	return newEOFError(position{offset: int64(m.pos)}, "unexpected end of JSON")
+}
+
+// skipValue advances past the value at the current position
+func (m *materializer) skipValue() error {
+	m.skipWhitespace()
+	if m.pos < len(m.data) && (m.data[m.pos] == '{' || m.data[m.pos] == '[') {
+		return m.skipContainer()
+	}
+	_, err := m.value()
+	return err
+}
+
+// string materializes a JSON string, decoding escape sequences
+// Unpaired surrogates become U+FFFD as in encoding/json
+func (m *materializer) string() (string, error) {
+	if !m.consume('"') {
+		return "", m.syntaxError("expected '\"'")
+	}
+
+	var result []byte
+	for m.pos < len(m.data) {
+		c := m.data[m.pos]
+		switch {
+		case c == '"':
+			m.pos++
+			return string(result), nil
+		case c != '\\':
+			result = append(result, c)
+			m.pos++
+			continue
+		}
+
+		if m.pos+1 >= len(m.data) {
+			break
+		}
+		switch esc := m.data[m.pos+1]; esc {
+		case '"', '\\', '/':
+			result = append(result, esc)
+		case 'b':
+			result = append(result, '\b')
+		case 'f':
+			result = append(result, '\f')
+		case 'n':
+			result = append(result, '\n')
+		case 'r':
+			result = append(result, '\r')
+		case 't':
+			result = append(result, '\t')
+		case 'u':
+			r, ok := m.unicodeEscape(m.pos)
+			if !ok {
+				return "", newEscapeError(position{offset: int64(m.pos)}, "invalid unicode escape sequence")
+			}
+			m.pos += 6
+			if isHighSurrogate(r) {
+				if low, ok := m.unicodeEscape(m.pos); ok && isLowSurrogate(low) {
+					r = decodeSurrogatePair(r, low)
+					m.pos += 6
+				}
+			}
+			result = append(result, encodeUTF8Rune(r)...)
+			continue
+		default:
+			return "", newEscapeError(position{offset: int64(m.pos)}, "invalid escape character: \\"+string(esc))
+		}
+		m.pos += 2
+	}
+
+	return "", newEOFError(position{offset: int64(m.pos)}, "unterminated string")
+}
+
+// unicodeEscape decodes a \uXXXX sequence at pos
+func (m *materializer) unicodeEscape(pos int) (rune, bool) {
+	if pos+6 > len(m.data) || m.data[pos] != '\\' || m.data[pos+1] != 'u' {
+		return 0, false
+	}
+	r, err := decodeUnicodeEscape(string(m.data[pos+2 : pos+6]))
+	return r, err == nil
+}
+
+// number materializes a JSON number, as float64 unless number options apply
+func (m *materializer) number() (interface{}, error) {
+	start := m.pos
+	for m.pos < len(m.data) {
+		c := m.data[m.pos]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		m.pos++
+	}
+	token := string(m.data[start:m.pos])
+	if !validNumber(token) {
+		return nil, newSyntaxError(position{offset: int64(start)}, "invalid number: "+token)
+	}
+
+	if len(m.opts.numberPaths) > 0 && m.opts.numberPaths[strings.Join(m.path, ".")] {
+		return json.Number(token), nil
+	}
+	if m.convert != nil {
+		return m.convert(token)
+	}
+
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, newSyntaxError(position{offset: int64(start)}, "invalid number: "+token)
+	}
+	return f, nil
+}
+
+// validNumber checks if token follows the JSON number grammar: an optional minus sign,
+// an integer part without leading zeros, an optional fraction and an optional exponent
+func validNumber(token string) bool {
+	i := 0
+	digits := func() bool {
+		start := i
+		for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			i++
+		}
+		return i > start
+	}
+
+	if i < len(token) && token[i] == '-' {
+		i++
+	}
+	if i < len(token) && token[i] == '0' {
+		i++
+	} else if !digits() {
+		return false
+	}
+	if i < len(token) && token[i] == '.' {
+		i++
+		if !digits() {
+			return false
+		}
+	}
+	if i < len(token) && (token[i] == 'e' || token[i] == 'E') {
+		i++
+		if i < len(token) && (token[i] == '+' || token[i] == '-') {
+			i++
+		}
+		if !digits() {
+			return false
+		}
+	}
+	return i == len(token)
+}
+
+// literal consumes the expected literal
+func (m *materializer) literal(expected string) error {
+	if m.pos+len(expected) > len(m.data) || string(m.data[m.pos:m.pos+len(expected)]) != expected {
+		return m.syntaxError("invalid literal")
+	}
+	m.pos += len(expected)
+	return nil
+}
+
+// tracksPath checks if any option needs the path of the current value
+func (m *materializer) tracksPath() bool {
+	return len(m.opts.numberPaths) > 0 || m.opts.valueHook != nil
+}
+
+// pushPath enters an object member or array element
+func (m *materializer) pushPath(segment string) {
+	if m.tracksPath() {
+		m.path = append(m.path, segment)
+	}
+}
+
+// popPath leaves the member or element entered by pushPath
+func (m *materializer) popPath() {
+	if m.tracksPath() {
+		m.path = m.path[:len(m.path)-1]
+	}
+}
+
+// consume advances past c if it is the current byte
+func (m *materializer) consume(c byte) bool {
+	if m.pos < len(m.data) && m.data[m.pos] == c {
+		m.pos++
+		return true
+	}
+	return false
+}
+
+// skipWhitespace skips insignificant whitespace
+func (m *materializer) skipWhitespace() {
+	for m.pos < len(m.data) {
+		switch m.data[m.pos] {
+		case ' ', '\t', '\n', '\r':
+			m.pos++
+		default:
+			return
+		}
+	}
+}
+
+// syntaxError creates a syntax error at the current position
+func (m *materializer) syntaxError(message string) *Error {
+	return newSyntaxError(position{offset: int64(m.pos)}, message)
+}
@@ -0,0 +1,343 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithNoStdlib_MatchesStdlib(t *testing.T) {
+	corpus := []string{
+		`{"name": "test", "value": 42, "active": true, "none": null}`,
+		`garbage [1, -2.5, 3e2, "x", false, null, {}, []] more`,
+		`{"nested": {"array": [{"a": 1}, {"b": [true]}]}}`,
+		`{"escapes": "q\"b\\s\/n\nt\tué emoji 😀"}`,
+		`{"lone": "\uD800 and \uDC00"}`,
+		`{"unicode": "日本語 🚀", "": "empty key"}`,
+		`{"big": 12345678901234567890, "small": 1e-10, "neg": -0}`,
+		`prefix {"short": 1} {"longer": {"nested": {"deep": "value"}}} suffix`,
+		`{"control": "a` + "\t" + `b"}`,
+	}
+
+	for _, input := range corpus {
+		var expected interface{}
+		if err := Unmarshal([]byte(input), &expected); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", input, err)
+		}
+
+		var unmarshaled interface{}
+		if err := Unmarshal([]byte(input), &unmarshaled, WithNoStdlib(true)); err != nil {
+			t.Fatalf("Unmarshal(%s) with WithNoStdlib failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(unmarshaled, expected) {
+			t.Errorf("Unmarshal(%s) with WithNoStdlib = %#v, expected %#v", input, unmarshaled, expected)
+		}
+
+		var decodedExpected, decoded interface{}
+		if err := New(strings.NewReader(input)).Decode(&decodedExpected); err != nil {
+			t.Fatalf("Decode(%s) failed: %v", input, err)
+		}
+		if err := New(strings.NewReader(input), WithNoStdlib(true)).Decode(&decoded); err != nil {
+			t.Fatalf("Decode(%s) with WithNoStdlib failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(decoded, decodedExpected) {
+			t.Errorf("Decode(%s) with WithNoStdlib = %#v, expected %#v", input, decoded, decodedExpected)
+		}
+	}
+}
+
+func TestWithNoStdlib_Destinations(t *testing.T) {
+	var obj map[string]interface{}
+	if err := Unmarshal([]byte(`{"a": 1}`), &obj, WithNoStdlib(true)); err != nil {
+		t.Fatalf("Unmarshal into map failed: %v", err)
+	}
+	if obj["a"] != float64(1) {
+		t.Errorf("Unexpected map result: %v", obj)
+	}
+
+	var arr []interface{}
+	if err := Unmarshal([]byte(`[1]`), &arr, WithNoStdlib(true)); err != nil {
+		t.Fatalf("Unmarshal into slice failed: %v", err)
+	}
+	if len(arr) != 1 {
+		t.Errorf("Unexpected slice result: %v", arr)
+	}
+
+	// Mismatched and unsupported destinations are rejected
+	if err := Unmarshal([]byte(`[1]`), &obj, WithNoStdlib(true)); err == nil {
+		t.Error("Expected error decoding array into map")
+	}
+	var typed struct{ A int }
+	if err := Unmarshal([]byte(`{"A": 1}`), &typed, WithNoStdlib(true)); err == nil {
+		t.Error("Expected error for struct destination")
+	}
+}
+
+func TestWithLazyBelowDepth(t *testing.T) {
+	input := `log: {"id": 1, "user": {"name": "alice", "tags": ["a", {"b": 2}]}, "list": [[1], {"x": "}"}]}`
+
+	var result map[string]interface{}
+	if err := Unmarshal([]byte(input), &result, WithLazyBelowDepth(2)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if result["id"] != float64(1) {
+		t.Errorf("id = %#v, expected 1", result["id"])
+	}
+	user, ok := result["user"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("user = %#v, expected decoded object at depth 2", result["user"])
+	}
+	if user["name"] != "alice" {
+		t.Errorf("user.name = %#v, expected alice", user["name"])
+	}
+	if tags, ok := user["tags"].(json.RawMessage); !ok || string(tags) != `["a",{"b":2}]` {
+		t.Errorf("user.tags = %#v, expected RawMessage at depth 3", user["tags"])
+	}
+
+	list, ok := result["list"].([]interface{})
+	if !ok || len(list) != 2 {
+		t.Fatalf("list = %#v, expected decoded array at depth 2", result["list"])
+	}
+	if raw, ok := list[0].(json.RawMessage); !ok || string(raw) != `[1]` {
+		t.Errorf("list[0] = %#v, expected RawMessage", list[0])
+	}
+	if raw, ok := list[1].(json.RawMessage); !ok || string(raw) != `{"x":"}"}` {
+		t.Errorf("list[1] = %#v, expected RawMessage", list[1])
+	}
+
+	// The raw values hold valid JSON that decodes on demand
+	var tags []interface{}
+	if err := json.Unmarshal(user["tags"].(json.RawMessage), &tags); err != nil || len(tags) != 2 {
+		t.Errorf("Unmarshal of raw tags = %v, %v", tags, err)
+	}
+}
+
+func TestWithLazyBelowDepth_InvalidNumbers(t *testing.T) {
+	// Clean input must not reach the materializer without the parser's validation
+	for _, input := range []string{`{"a":01}`, `{"a":1.}`, `{"a":-}`, `{"a":1e}`, `{"a":.5}`} {
+		var result map[string]interface{}
+		if err := Unmarshal([]byte(input), &result, WithLazyBelowDepth(1)); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, expected error as without the option", input, result)
+		}
+	}
+}
+
+func TestValidNumber(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected bool
+	}{
+		{"0", true},
+		{"-0", true},
+		{"12", true},
+		{"1.5", true},
+		{"-1.5e+10", true},
+		{"2E-3", true},
+		{"1e5", true},
+		{"", false},
+		{"-", false},
+		{"01", false},
+		{"-01", false},
+		{"1.", false},
+		{".5", false},
+		{"+1", false},
+		{"1e", false},
+		{"1e+", false},
+		{"1.2.3", false},
+		{"1e5e5", false},
+		{"1-2", false},
+	}
+
+	for _, test := range tests {
+		if result := validNumber(test.token); result != test.expected {
+			t.Errorf("validNumber(%q) = %v, expected %v", test.token, result, test.expected)
+		}
+	}
+
+	// The materializer enforces the grammar even on data the parser did not validate
+	if _, err := materialize([]byte(`{"a":01}`), defaultOptions()); err == nil {
+		t.Error("materialize accepted a number with a leading zero")
+	}
+}
+
+func TestWithLazyBelowDepth_TopLevel(t *testing.T) {
+	var result interface{}
+	if err := New(strings.NewReader(`noise [{"a": [1]}, 2]`), WithLazyBelowDepth(1)).Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	expected := []interface{}{json.RawMessage(`{"a":[1]}`), float64(2)}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Decode = %#v, expected %#v", result, expected)
+	}
+}
+
+func TestWithNumberPaths(t *testing.T) {
+	input := `txn: {"amount": 1234.5678901234567890, "count": 3, "items": [{"price": 0.10}, {"price": 2}], "meta": {"amount": 7}}`
+
+	var result map[string]interface{}
+	opts := WithNumberPaths([]string{"amount", "items.1.price", "meta.amount"})
+	if err := Unmarshal([]byte(input), &result, opts); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if result["amount"] != json.Number("1234.5678901234567890") {
+		t.Errorf("amount = %#v, expected json.Number", result["amount"])
+	}
+	if result["count"] != float64(3) {
+		t.Errorf("count = %#v, expected float64", result["count"])
+	}
+	items := result["items"].([]interface{})
+	if price := items[0].(map[string]interface{})["price"]; price != 0.1 {
+		t.Errorf("items.0.price = %#v, expected float64", price)
+	}
+	if price := items[1].(map[string]interface{})["price"]; price != json.Number("2") {
+		t.Errorf("items.1.price = %#v, expected json.Number", price)
+	}
+	if amount := result["meta"].(map[string]interface{})["amount"]; amount != json.Number("7") {
+		t.Errorf("meta.amount = %#v, expected json.Number", amount)
+	}
+
+	var decoded interface{}
+	if err := New(strings.NewReader(input), opts).Decode(&decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, interface{}(result)) {
+		t.Errorf("Decode = %#v, expected %#v", decoded, result)
+	}
+}
+
+func TestWithNumberPaths_InvalidNumber(t *testing.T) {
+	var result map[string]interface{}
+	if err := Unmarshal([]byte(`{"a":01}`), &result, WithNumberPaths([]string{"a"})); err == nil {
+		t.Errorf("Unmarshal = %#v, expected error for a number with a leading zero", result)
+	}
+}
+
+func TestWithValueHook(t *testing.T) {
+	input := `login: {"user": {"name": "alice", "password": "secret", "keys": [{"password": "k1"}]}, "password": 1234}`
+
+	var paths []string
+	redact := WithValueHook(func(path []string, value interface{}) interface{} {
+		paths = append(paths, strings.Join(path, "."))
+		if len(path) > 0 && path[len(path)-1] == "password" {
+			return "***"
+		}
+		return value
+	})
+
+	var result map[string]interface{}
+	if err := Unmarshal([]byte(input), &result, redact); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"user": map[string]interface{}{
+			"name":     "alice",
+			"password": "***",
+			"keys":     []interface{}{map[string]interface{}{"password": "***"}},
+		},
+		"password": "***",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unmarshal = %#v, expected %#v", result, expected)
+	}
+
+	expectedPaths := []string{"user.name", "user.password", "user.keys.0.password", "password"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("Hook paths = %v, expected %v", paths, expectedPaths)
+	}
+}
+
+func TestWithValueHook_InvalidInput(t *testing.T) {
+	inputs := []string{`{"a":01}`, `{"a":1.}`, `{"a":"\q"}`, `{"a":tru}`, `{"a":1,}`}
+
+	for _, input := range inputs {
+		if json.Valid([]byte(input)) {
+			t.Fatalf("%s is valid JSON", input)
+		}
+
+		var seen []interface{}
+		hook := WithValueHook(func(path []string, value interface{}) interface{} {
+			seen = append(seen, value)
+			return value
+		})
+
+		var result map[string]interface{}
+		if err := Unmarshal([]byte(input), &result, hook); err == nil {
+			t.Errorf("Unmarshal(%q) = %#v, expected error", input, result)
+		}
+		if len(seen) > 0 {
+			t.Errorf("Hook saw %#v from %q, which encoding/json rejects", seen, input)
+		}
+	}
+}
+
+func TestWithDuplicateKeysAsArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Two repetitions",
+			data:     `{"tag": "a", "tag": "b", "id": 1}`,
+			expected: map[string]interface{}{"tag": []interface{}{"a", "b"}, "id": float64(1)},
+		},
+		{
+			name:     "Three repetitions",
+			data:     `noise {"tag": "a", "other": true, "tag": "b", "tag": "c"}`,
+			expected: map[string]interface{}{"tag": []interface{}{"a", "b", "c"}, "other": true},
+		},
+		{
+			name: "Mixed scalar and object values",
+			data: `{"v": 1, "v": {"x": [1]}, "v": null}`,
+			expected: map[string]interface{}{"v": []interface{}{
+				float64(1), map[string]interface{}{"x": []interface{}{float64(1)}}, nil,
+			}},
+		},
+		{
+			name: "Array value is not extended",
+			data: `{"list": [1, 2], "list": 3}`,
+			expected: map[string]interface{}{"list": []interface{}{
+				[]interface{}{float64(1), float64(2)}, float64(3),
+			}},
+		},
+		{
+			name: "Nested objects are independent",
+			data: `{"a": {"k": 1}, "b": {"k": 2, "k": 3}}`,
+			expected: map[string]interface{}{
+				"a": map[string]interface{}{"k": float64(1)},
+				"b": map[string]interface{}{"k": []interface{}{float64(2), float64(3)}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result map[string]interface{}
+			if err := Unmarshal([]byte(test.data), &result, WithDuplicateKeysAsArray(true)); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("Unmarshal = %#v, expected %#v", result, test.expected)
+			}
+		})
+	}
+
+	// By default the last value wins
+	var result map[string]interface{}
+	if err := Unmarshal([]byte(`{"tag": "a", "tag": "b"}`), &result); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["tag"] != "b" {
+		t.Errorf("Unmarshal = %v, expected last value", result)
+	}
+
+	// Invalid input is rejected as without the option
+	result = nil
+	if err := Unmarshal([]byte(`{"tag": 01, "tag": 1.}`), &result, WithDuplicateKeysAsArray(true)); err == nil {
+		t.Errorf("Unmarshal = %v, expected error for invalid numbers", result)
+	}
+}
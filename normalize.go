@@ -0,0 +1,202 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Normalize extracts the JSON value that Unmarshal would decode and returns it in a
+// canonical form: insignificant whitespace is removed and strings are written with the
+// same escapes as Marshal, while numbers are kept as written. Values extracted from
+// different noisy sources can then be compared byte by byte. With WithSortKeys, object
+// keys are also sorted for a deterministic representation
+func Normalize(data []byte, opts ...Option) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, newNoJSONError(position{}, "empty input data")
+	}
+
+	options := applyOptions(opts...)
+	if err := checkInputSize(data, options); err != nil {
+		return nil, err
+	}
+	data = prepareInput(data, options)
+
+	jsonBytes, _, _, err := parseSelected(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &normalizer{data: jsonBytes, sortKeys: options.sortKeys, rawSeparators: options.rawSeparators}
+	return n.value(make([]byte, 0, len(jsonBytes)))
+}
+
+// normalizer rewrites the compact JSON emitted by the parser in canonical form
+type normalizer struct {
+	data          []byte
+	pos           int
+	sortKeys      bool // write object members in lexicographic order of their keys
+	rawSeparators bool // write U+2028 and U+2029 unescaped
+}
+
+// member is an object member written by the normalizer, with its key decoded
+type member struct {
+	key   string
+	start int // offset of the member in the output
+	end   int
+}
+
+// value appends the canonical form of the value at the current position to dst
+func (n *normalizer) value(dst []byte) ([]byte, error) {
+	switch n.data[n.pos] {
+	case '{':
+		return n.container(dst, '}')
+	case '[':
+		return n.container(dst, ']')
+	case '"':
+		s, err := n.str()
+		if err != nil {
+			return nil, err
+		}
+		return appendString(dst, s, n.rawSeparators), nil
+	}
+
+	// Numbers and literals run up to the next delimiter
+	end := n.pos
+	for end < len(n.data) && n.data[end] != ',' && n.data[end] != ']' && n.data[end] != '}' {
+		end++
+	}
+	dst = append(dst, n.data[n.pos:end]...)
+	n.pos = end
+	return dst, nil
+}
+
+// container appends the object or array at the current position, which ends with closing
+func (n *normalizer) container(dst []byte, closing byte) ([]byte, error) {
+	dst = append(dst, n.data[n.pos])
+	n.pos++
+	open := len(dst)
+
+	var members []member
+	for n.data[n.pos] != closing {
+		start := len(dst)
+		if closing == '}' {
+			key, err := n.str()
+			if err != nil {
+				return nil, err
+			}
+			dst = appendString(dst, key, n.rawSeparators)
+			members = append(members, member{key: key, start: start})
+
+			// The colon after the key
+			dst = append(dst, ':')
+			n.pos++
+		}
+
+		var err error
+		if dst, err = n.value(dst); err != nil {
+			return nil, err
+		}
+		if len(members) > 0 {
+			members[len(members)-1].end = len(dst)
+		}
+		if n.data[n.pos] == ',' {
+			dst = append(dst, ',')
+			n.pos++
+		}
+	}
+	n.pos++
+
+	if n.sortKeys && len(members) > 1 {
+		dst = sortMembers(dst, open, members)
+	}
+	return append(dst, closing), nil
+}
+
+// sortMembers rewrites the members of an object, written to dst from offset open on,
+// in lexicographic order of their keys. Members with equal keys keep their order
+func sortMembers(dst []byte, open int, members []member) []byte {
+	written := slices.Clone(dst[open:])
+	slices.SortStableFunc(members, func(a, b member) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	dst = dst[:open]
+	for i, m := range members {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, written[m.start-open:m.end-open]...)
+	}
+	return dst
+}
+
+// str reads the string at the current position and returns it decoded
+func (n *normalizer) str() (string, error) {
+	start := n.pos
+	escaped := false
+	for n.pos++; n.data[n.pos] != '"'; n.pos++ {
+		if n.data[n.pos] == '\\' {
+			escaped = true
+			n.pos++
+		}
+	}
+	n.pos++
+
+	token := n.data[start:n.pos]
+	s := string(token[1 : len(token)-1])
+	if escaped {
+		if err := json.Unmarshal(token, &s); err != nil {
+			return "", wrapDecodeError(err)
+		}
+	}
+	return s, nil
+}
+
+// appendString appends s as a JSON string escaped like Marshal: quotes, backslashes,
+// control characters and, unless rawSeparators is set, the line and paragraph separators
+// are escaped
+func appendString(dst []byte, s string, rawSeparators bool) []byte {
+	const hex = "0123456789abcdef"
+
+	dst = append(dst, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if (r == '\u2028' || r == '\u2029') && !rawSeparators {
+				dst = append(dst, `\u202`...)
+				dst = append(dst, hex[r&0xF])
+			} else {
+				dst = append(dst, s[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\b':
+			dst = append(dst, `\b`...)
+		case '\f':
+			dst = append(dst, `\f`...)
+		case '\n':
+			dst = append(dst, `\n`...)
+		case '\r':
+			dst = append(dst, `\r`...)
+		case '\t':
+			dst = append(dst, `\t`...)
+		default:
+			if c < 0x20 {
+				dst = append(dst, `\u00`...)
+				dst = append(dst, hex[c>>4], hex[c&0xF])
+			} else {
+				dst = append(dst, c)
+			}
+		}
+		i++
+	}
+	return append(dst, '"')
+}
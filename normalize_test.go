@@ -0,0 +1,103 @@
+package jsonex
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+	}{
+		{"Whitespace", "log: { \"a\" : [ 1 , 2 ] ,\n \"b\" : { } } end", nil, `{"a":[1,2],"b":{}}`},
+		{"Numbers as written", `[1.50, -0, 2e+3, 10E2]`, nil, `[1.50,-0,2e+3,10E2]`},
+		{"Unicode escapes", `{"\u0041": "caf\u00e9 \ud83d\ude00"}`, nil, `{"A":"café 😀"}`},
+		{"Short escapes", `["\/", "\u000a", "\t", "\u001f", "\"\\"]`, nil, `["/","\n","\t","\u001f","\"\\"]`},
+		{"Separators", "[\"\\u2028\", \"\u2029\"]", nil, `["\u2028","\u2029"]`},
+		{"Raw separators", "{\"\\u2028\": \"\u2029\"}", []Option{WithEscapeLineSeparators(false)}, "{\"\u2028\":\"\u2029\"}"},
+		{"Raw control character", "[\"a\x01b\"]", nil, `["a\u0001b"]`},
+		{"Lone surrogate", `["\udc00"]`, nil, `["` + "\uFFFD" + `"]`},
+		{"Lenient input", "[True, /* c */ None, 1,]", []Option{WithLenientLiterals(true), WithAllowComments(), WithAllowTrailingCommas()}, `[true,null,1]`},
+		{"Scalar", `value: "x\u0079"`, []Option{WithAllowScalars(true)}, `"xy"`},
+		{"Windows path", `{"p": "C:\Users"}`, []Option{WithWindowsPathStrings(true)}, `{"p":"C:\\Users"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, err := Normalize([]byte(test.input), test.opts...)
+			if err != nil {
+				t.Fatalf("Normalize failed: %v", err)
+			}
+			if string(normalized) != test.expected {
+				t.Errorf("Normalize(%s) = %s, expected %s", test.input, normalized, test.expected)
+			}
+		})
+	}
+
+	// The same value from differently formatted sources normalizes to the same bytes
+	a, errA := Normalize([]byte(`2024-01-01 INFO {"msg": "caf\u00e9", "n": [1, 2]}`))
+	b, errB := Normalize([]byte("{\n  \"msg\": \"café\",\n  \"n\": [1,2]\n} -- trailer"))
+	if errA != nil || errB != nil || string(a) != string(b) {
+		t.Errorf("Normalize = %s, %s (%v, %v), expected equal output", a, b, errA, errB)
+	}
+
+	if _, err := Normalize([]byte("no json")); !errors.Is(err, ErrNoJSON) {
+		t.Errorf("Normalize without JSON = %v, expected ErrNoJSON", err)
+	}
+}
+
+func TestNormalize_WithSortKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Flat", `{"b": 1, "a": 2, "c": 3}`, `{"a":2,"b":1,"c":3}`},
+		{"Nested", `{"z": {"y": 1, "x": [{"d": 1, "c": 2}]}, "a": null}`, `{"a":null,"z":{"x":[{"c":2,"d":1}],"y":1}}`},
+		{"Escaped keys", `{"\u0062": 1, "a\n": 2, "a": 3}`, `{"a":3,"a\n":2,"b":1}`},
+		{"Duplicate keys keep order", `{"b": 1, "a": 2, "b": 3}`, `{"a":2,"b":1,"b":3}`},
+		{"Array order kept", `[{"b": 1, "a": 2}, 3, 1]`, `[{"a":2,"b":1},3,1]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalized, err := Normalize([]byte(test.input), WithSortKeys())
+			if err != nil {
+				t.Fatalf("Normalize failed: %v", err)
+			}
+			if string(normalized) != test.expected {
+				t.Errorf("Normalize(%s) = %s, expected %s", test.input, normalized, test.expected)
+			}
+		})
+	}
+
+	// Key order of the source does not matter with sorted keys
+	a, errA := Normalize([]byte(`{"a": 1, "b": {"c": 2, "d": 3}}`), WithSortKeys())
+	b, errB := Normalize([]byte(`data: {"b": {"d": 3, "c": 2}, "a": 1}`), WithSortKeys())
+	if errA != nil || errB != nil || string(a) != string(b) {
+		t.Errorf("Normalize = %s, %s (%v, %v), expected equal output", a, b, errA, errB)
+	}
+
+	// Without the option, keys stay in source order
+	unsorted, err := Normalize([]byte(`{"b": 1, "a": 2}`))
+	if err != nil || string(unsorted) != `{"b":1,"a":2}` {
+		t.Errorf("Normalize = %s, %v, expected keys in source order", unsorted, err)
+	}
+}
+
+func TestNormalize_MatchesMarshal(t *testing.T) {
+	strings := []string{"plain", "<&>", "quote \" backslash \\ slash /", "\b\f\n\r\t\x00\x1f\x7f", "é 😀 \u2028\u2029"}
+
+	for _, s := range strings {
+		marshaled, err := Marshal(s)
+		if err != nil {
+			t.Fatalf("Marshal(%q) failed: %v", s, err)
+		}
+		if got := string(appendString(nil, s, false)); got != string(marshaled) {
+			t.Errorf("appendString(%q) = %s, expected %s as from Marshal", s, got, marshaled)
+		}
+	}
+}
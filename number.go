@@ -0,0 +1,129 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// numberConverter returns a function converting number tokens decoded into interface{}
+// according to the options, or nil when the default float64 conversion applies
+func numberConverter(opts options) func(token string) (interface{}, error) {
+	if !opts.useNumber && !opts.integerNumbers && opts.impreciseNumberHandler == nil {
+		return nil
+	}
+
+	return func(token string) (interface{}, error) {
+		if opts.useNumber {
+			return json.Number(token), nil
+		}
+
+		if opts.integerNumbers && isIntegerToken(token) {
+			if n, err := strconv.ParseInt(token, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+
+		f, err := strconv.ParseFloat(token, 64)
+		if opts.impreciseNumberHandler != nil && (err != nil || !isExactFloat(token, f)) {
+			return opts.impreciseNumberHandler(token)
+		}
+		if err != nil {
+			return nil, newSyntaxError(position{}, "invalid number: "+token)
+		}
+		return f, nil
+	}
+}
+
+// isIntegerToken checks if a number token has neither a fraction nor an exponent
+func isIntegerToken(token string) bool {
+	return !strings.ContainsAny(token, ".eE")
+}
+
+// resolveNumbers replaces json.Number values held in interface{} slots of v
+// with the result of convert
+func resolveNumbers(v reflect.Value, convert func(token string) (interface{}, error)) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return resolveNumbers(v.Elem(), convert)
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		number, ok := v.Elem().Interface().(json.Number)
+		if !ok {
+			// Maps and slices are references, so walking the copy updates them in place
+			return resolveNumbers(v.Elem(), convert)
+		}
+		resolved, err := convert(number.String())
+		if err != nil {
+			return err
+		}
+		if resolved == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(resolved))
+		}
+		return nil
+
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			// Map values are not addressable; resolve a copy and store it back
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(iter.Value())
+			if err := resolveNumbers(value, convert); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveNumbers(v.Index(i), convert); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				if err := resolveNumbers(field, convert); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isExactFloat checks if f has the same numeric value as the decimal token
+// Decimal fractions like 0.1 are considered exact when they round-trip through f
+func isExactFloat(token string, f float64) bool {
+	// Tokens out of the float64 range are never exact, and big.Rat takes time growing
+	// with their exponent, so they are decided without it
+	if math.IsInf(f, 0) {
+		return false
+	}
+	if f == 0 {
+		mantissa, _, _ := strings.Cut(strings.ToLower(token), "e")
+		return strings.Trim(mantissa, "-0.") == ""
+	}
+
+	want, ok := new(big.Rat).SetString(token)
+	if !ok {
+		return false
+	}
+	got, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	if !ok {
+		return false
+	}
+	return want.Cmp(got) == 0
+}
@@ -0,0 +1,208 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsExactFloat(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected bool
+	}{
+		{"0", true},
+		{"0.1", true},
+		{"-1.5e3", true},
+		{"9007199254740992", true},   // 2^53
+		{"9007199254740993", false},  // 2^53 + 1
+		{"-9007199254740993", false}, // -(2^53 + 1)
+		{"18014398509481984", true},  // 2^54
+		{"10000000000000001", false},
+		{"0.30000000000000000001", false},
+		{"-0.0e-5", true},
+		{"1e-400", false},
+		{"1e400", false},
+	}
+
+	for _, test := range tests {
+		f, _ := new(big.Float).SetString(test.token)
+		value, _ := f.Float64()
+		if result := isExactFloat(test.token, value); result != test.expected {
+			t.Errorf("isExactFloat(%s) = %v, expected %v", test.token, result, test.expected)
+		}
+	}
+}
+
+func TestIsExactFloat_HugeExponent(t *testing.T) {
+	// Parsing these as big.Rat took tens of milliseconds per token, a minute for this test
+	for _, token := range []string{"1e-1000000", "-1e1000000", "0.5E-999999999"} {
+		f, _ := strconv.ParseFloat(token, 64)
+		for range 1000 {
+			if isExactFloat(token, f) {
+				t.Fatalf("isExactFloat(%s) = true, expected false", token)
+			}
+		}
+	}
+}
+
+func TestWithImpreciseNumberHandler(t *testing.T) {
+	var called []string
+	handler := func(token string) (interface{}, error) {
+		called = append(called, token)
+		return json.Number(token), nil
+	}
+
+	data := []byte(`log {"exact": 9007199254740992, "imprecise": 9007199254740993, "list": [0.1, -9007199254740993], "huge": 1e400}`)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithImpreciseNumberHandler(handler)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if result["exact"] != float64(9007199254740992) {
+		t.Errorf("exact = %#v, expected float64", result["exact"])
+	}
+	if result["imprecise"] != json.Number("9007199254740993") {
+		t.Errorf("imprecise = %#v, expected json.Number", result["imprecise"])
+	}
+	list := result["list"].([]interface{})
+	if list[0] != 0.1 || list[1] != json.Number("-9007199254740993") {
+		t.Errorf("list = %#v", list)
+	}
+	if result["huge"] != json.Number("1e400") {
+		t.Errorf("huge = %#v, expected json.Number", result["huge"])
+	}
+	if len(called) != 3 {
+		t.Errorf("Handler called for %v, expected 3 tokens", called)
+	}
+}
+
+func TestWithImpreciseNumberHandler_Decoder(t *testing.T) {
+	handler := func(token string) (interface{}, error) {
+		n, _ := new(big.Int).SetString(token, 10)
+		return n, nil
+	}
+
+	var result struct {
+		V interface{}
+		N json.Number
+	}
+	input := `{"V": 9007199254740993, "N": 9007199254740993}`
+	if err := New(strings.NewReader(input), WithImpreciseNumberHandler(handler)).Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if n, ok := result.V.(*big.Int); !ok || n.String() != "9007199254740993" {
+		t.Errorf("V = %#v, expected *big.Int", result.V)
+	}
+	if result.N != "9007199254740993" {
+		t.Errorf("N = %#v, expected json.Number untouched", result.N)
+	}
+}
+
+func TestWithImpreciseNumberHandler_Error(t *testing.T) {
+	errImprecise := errors.New("imprecise number")
+	handler := func(token string) (interface{}, error) {
+		return nil, errImprecise
+	}
+
+	var result interface{}
+	err := Unmarshal([]byte(`[1, 2, 9007199254740993]`), &result, WithImpreciseNumberHandler(handler))
+	if !errors.Is(err, errImprecise) {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+}
+
+func TestNumberOptions_TrailingData(t *testing.T) {
+	handler := func(token string) (interface{}, error) {
+		return json.Number(token), nil
+	}
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"UseNumber", WithUseNumber()},
+		{"IntegerNumbers", WithIntegerNumbers(true)},
+		{"ImpreciseNumberHandler", WithImpreciseNumberHandler(handler)},
+		{"DisallowUnknownFields", WithDisallowUnknownFields()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// A clean value followed by a longer one: the longer one wins as without the option
+			data := []byte(`{"a":1} {"bbbbbbbb":2}`)
+			var result map[string]interface{}
+			start, end, err := UnmarshalAt(data, &result, test.opt)
+			if err != nil {
+				t.Fatalf("UnmarshalAt failed: %v", err)
+			}
+			if len(result) != 1 || result["bbbbbbbb"] == nil {
+				t.Errorf("UnmarshalAt = %v, expected only the longer value", result)
+			}
+			if start != 8 || end != 22 {
+				t.Errorf("UnmarshalAt offsets = %d, %d, expected 8, 22", start, end)
+			}
+
+			var strict map[string]interface{}
+			if err := Unmarshal([]byte(`{"a":1} junk`), &strict, test.opt, WithStrictBoundaries()); err == nil {
+				t.Errorf("Unmarshal with strict boundaries accepted trailing data: %v", strict)
+			}
+		})
+	}
+}
+
+func TestWithIntegerNumbers(t *testing.T) {
+	var result struct {
+		V     interface{}
+		F     interface{}
+		Big   interface{}
+		List  []interface{}
+		Typed float64
+	}
+	data := []byte(`{"V": 5, "F": 5.5, "Big": 1e3, "List": [1, -2, 3.0, 9223372036854775808], "Typed": 7}`)
+	if err := Unmarshal(data, &result, WithIntegerNumbers(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if result.V != int64(5) {
+		t.Errorf("V = %#v, expected int64(5)", result.V)
+	}
+	if result.F != 5.5 {
+		t.Errorf("F = %#v, expected float64(5.5)", result.F)
+	}
+	if result.Big != float64(1000) {
+		t.Errorf("Big = %#v, expected float64(1000)", result.Big)
+	}
+	expectedList := []interface{}{int64(1), int64(-2), float64(3), float64(9223372036854775808)}
+	if !reflect.DeepEqual(result.List, expectedList) {
+		t.Errorf("List = %#v, expected %#v", result.List, expectedList)
+	}
+	if result.Typed != 7 {
+		t.Errorf("Typed = %v, expected 7", result.Typed)
+	}
+}
+
+func TestWithIntegerNumbers_Decoder(t *testing.T) {
+	input := `noise {"v": 5, "nested": {"n": [10]}}`
+
+	for _, noStdlib := range []bool{false, true} {
+		var result map[string]interface{}
+		decoder := New(strings.NewReader(input), WithIntegerNumbers(true), WithNoStdlib(noStdlib))
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatalf("Decode (noStdlib=%v) failed: %v", noStdlib, err)
+		}
+
+		expected := map[string]interface{}{
+			"v":      int64(5),
+			"nested": map[string]interface{}{"n": []interface{}{int64(10)}},
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Decode (noStdlib=%v) = %#v, expected %#v", noStdlib, result, expected)
+		}
+	}
+}
@@ -1,9 +1,66 @@
 package jsonex
 
+import (
+	"bytes"
+	"io"
+)
+
+// Selection specifies which JSON value is extracted when the input contains several
+type Selection int
+
+const (
+	// Longest selects the longest valid JSON value (default)
+	Longest Selection = iota
+	// First selects the first valid JSON value
+	First
+	// Last selects the last of the non-overlapping valid JSON values
+	Last
+)
+
 // options holds internal configuration options (unexported)
 type options struct {
-	maxDepth   int // maximum nesting depth (default: 1000)
-	bufferSize int // read buffer size (default: 4096)
+	maxDepth        int               // maximum nesting depth (default: 1000)
+	maxInputSize    int               // maximum bytes of input read or examined (default: 0, unlimited)
+	maxGarbage      int               // maximum bytes skipped before the first value (default: 0, unlimited)
+	maxStringLen    int               // maximum bytes of a string between its quotes (default: 0, unlimited)
+	maxObjectKeys   int               // maximum keys of an object (default: 0, unlimited)
+	maxArrayElems   int               // maximum elements of an array (default: 0, unlimited)
+	bufferSize      int               // read buffer size (default: 4096)
+	strictUTF8      bool              // reject invalid UTF-8 in strings (default: true)
+	csvQuoting      bool              // un-double CSV quotes before parsing (default: false)
+	autoEncoding    bool              // transcode UTF-16/UTF-32 input with a byte order mark to UTF-8 (default: false)
+	windowSize      int               // sliding window size for UnmarshalReader (default: 0, unbounded)
+	valuePerReader  bool              // forbid values spanning readers of NewMulti (default: false)
+	perLine         bool              // extract at most one value per input line (default: false)
+	keepSkipped     bool              // collect garbage skipped before each value for Decoder.LastSkipped (default: false)
+	startMarker     string            // marker preceding each JSON value, with endMarker (default: "", disabled)
+	endMarker       string            // marker following each JSON value (default: "")
+	sortKeys        bool              // write object keys in lexicographic order in Normalize (default: false)
+	rawSeparators   bool              // write U+2028 and U+2029 unescaped in Normalize and Marshal (default: false)
+	lookahead       int               // bytes after a value searched for a longer one by Decoder (default: 0)
+	lenientLiterals bool              // accept alternate spellings of true/false/null (default: false)
+	allowComments   bool              // skip // and /* */ comments between tokens (default: false)
+	trailingCommas  bool              // accept a comma before '}' or ']' (default: false)
+	extraSpace      []rune            // runes skipped as whitespace in addition to the RFC 8259 ones (default: nil)
+	windowsPaths    bool              // keep backslashes of invalid escapes in strings (default: false)
+	noStdlib        bool              // materialize values without encoding/json (default: false)
+	selection       Selection         // which JSON value Unmarshal extracts (default: Longest)
+	strictBounds    bool              // reject content other than whitespace around the value (default: false)
+	integerNumbers  bool              // decode integer numbers in interface{} as int64 (default: false)
+	useNumber       bool              // decode numbers in interface{} as json.Number (default: false)
+	disallowUnknown bool              // reject object keys without a matching struct field (default: false)
+	tee             io.Writer         // receives a copy of every byte read from the input (default: nil)
+	allocBudget     func(n int) error // charged as extraction buffers grow (default: nil)
+	lazyDepth       int               // keep objects/arrays deeper than this as json.RawMessage (default: 0, disabled)
+	numberPaths     map[string]bool   // dotted paths of numbers decoded as json.Number (default: nil)
+	strictArrayLen  bool              // reject JSON arrays not matching fixed-size Go arrays (default: false)
+	collectDupKeys  bool              // collect values of repeated object keys into a slice (default: false)
+	disallowDupKeys bool              // reject repeated keys within an object (default: false)
+	allowScalars    bool              // accept strings, numbers, booleans and null as top-level values (default: false)
+
+	impreciseNumberHandler func(token string) (interface{}, error)            // handles numbers float64 cannot hold exactly
+	valueHook              func(path []string, value interface{}) interface{} // replaces scalar values as they are decoded (default: nil)
+	escapeHandler          func(b byte, r *bytes.Reader) ([]byte, error)      // decodes escapes in strings before the parser (default: nil)
 }
 
 // defaultOptions returns the default configuration
@@ -11,6 +68,7 @@ func defaultOptions() options {
 	return options{
 		maxDepth:   1000,
 		bufferSize: 4096,
+		strictUTF8: true,
 	}
 }
 
@@ -27,7 +85,61 @@ func WithMaxDepth(depth int) Option {
 	}
 }
 
-// WithBufferSize sets the read buffer size for performance tuning
+// WithMaxInputSize limits the input to n bytes. Unmarshal rejects longer data and
+// Decoder stops reading once n bytes have been read, with an ErrInvalidJSON error
+func WithMaxInputSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxInputSize = n
+		}
+	}
+}
+
+// WithMaxGarbagePrefix limits the garbage skipped before the first JSON value to n bytes,
+// so that input that is not JSON is rejected without searching all of it. A value starting
+// after n bytes is not extracted, and an ErrInvalidJSON error matching ErrNoValidJSON is
+// returned if none starts before. Decoder applies the limit to the stream before its
+// first value. WithDelimiters and WithStrictBoundaries do not use it
+func WithMaxGarbagePrefix(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxGarbage = n
+		}
+	}
+}
+
+// WithMaxStringLength limits strings, both object keys and values, to n bytes of input
+// between their quotes. A longer string fails the parse with an ErrSyntax error
+func WithMaxStringLength(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxStringLen = n
+		}
+	}
+}
+
+// WithMaxObjectKeys limits objects to n keys, counting repeated keys. An object with
+// more keys fails the parse with an ErrSyntax error
+func WithMaxObjectKeys(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxObjectKeys = n
+		}
+	}
+}
+
+// WithMaxArrayElements limits arrays to n elements. An array with more elements
+// fails the parse with an ErrSyntax error
+func WithMaxArrayElements(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.maxArrayElems = n
+		}
+	}
+}
+
+// WithBufferSize sets the read buffer size for performance tuning, which is also the
+// initial size of the buffer collecting each extracted value
 // Larger buffers may improve performance for large JSON files
 func WithBufferSize(size int) Option {
 	return func(o *options) {
@@ -37,6 +149,368 @@ func WithBufferSize(size int) Option {
 	}
 }
 
+// WithStrictUTF8 controls how invalid UTF-8 inside JSON strings is handled
+// When strict (default), invalid sequences are rejected with ErrUnicode.
+// Otherwise each invalid sequence is replaced with U+FFFD.
+// Bytes outside of JSON values are skipped as garbage in both modes
+func WithStrictUTF8(strict bool) Option {
+	return func(o *options) {
+		o.strictUTF8 = strict
+	}
+}
+
+// WithReplaceInvalidUTF8 replaces each invalid UTF-8 byte inside JSON strings with
+// U+FFFD instead of failing with ErrUnicode, for best-effort extraction from logs.
+// It is shorthand for WithStrictUTF8(false)
+func WithReplaceInvalidUTF8() Option {
+	return WithStrictUTF8(false)
+}
+
+// WithCSVQuoting enables un-doubling of CSV quotes ("" to ") before parsing
+// This allows extracting JSON embedded in quoted CSV fields such as "{""a"":1}"
+func WithCSVQuoting(enabled bool) Option {
+	return func(o *options) {
+		o.csvQuoting = enabled
+	}
+}
+
+// WithAutoDetectEncoding transcodes input that starts with a UTF-16 or UTF-32 byte order
+// mark to UTF-8 before parsing, as allowed by RFC 8259 section 8.1. The mark is removed.
+// Input without such a mark is parsed as UTF-8. Offsets refer to the transcoded input
+func WithAutoDetectEncoding() Option {
+	return func(o *options) {
+		o.autoEncoding = true
+	}
+}
+
+// WithLargeFileMode bounds the memory used by UnmarshalReader to a sliding window
+// of windowBytes. The longest-match heuristic is applied within each window and the
+// longest match across all windows is used. Windows overlap by half, so values up to
+// windowBytes/2 are always found, while values longer than windowBytes are never found
+func WithLargeFileMode(windowBytes int) Option {
+	return func(o *options) {
+		if windowBytes > 0 {
+			o.windowSize = windowBytes
+		}
+	}
+}
+
+// WithValuePerReader makes a Decoder created by NewMulti reject values that
+// start in one reader and continue in the next one
+func WithValuePerReader(enabled bool) Option {
+	return func(o *options) {
+		o.valuePerReader = enabled
+	}
+}
+
+// WithPerLineExtraction makes a Decoder treat each input line as a separate record:
+// the first JSON value of a line is extracted, the rest of the line is skipped, values
+// may not span lines and lines without JSON are skipped
+func WithPerLineExtraction(enabled bool) Option {
+	return func(o *options) {
+		o.perLine = enabled
+	}
+}
+
+// WithLookahead makes a Decoder look for a longer value starting within n bytes
+// after the end of each extracted value before returning it. A longer value
+// replaces the extracted one, approximating the longest match of Unmarshal with
+// bounded memory. Values that are not longer are returned by the next Decode
+func WithLookahead(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.lookahead = n
+		}
+	}
+}
+
+// WithLenientLiterals makes the parser accept case-insensitive true/false and
+// null/None/nil as null. Literals are emitted in their lowercase RFC 8259 form
+func WithLenientLiterals(enabled bool) Option {
+	return func(o *options) {
+		o.lenientLiterals = enabled
+	}
+}
+
+// WithAllowComments makes the parser skip JSONC-style // line comments and /* */ block
+// comments between tokens. Comments are not part of the extracted JSON
+func WithAllowComments() Option {
+	return func(o *options) {
+		o.allowComments = true
+	}
+}
+
+// WithAllowTrailingCommas makes the parser accept a comma right before '}' or ']'
+// The comma is removed from the extracted JSON, so it stays valid for encoding/json
+func WithAllowTrailingCommas() Option {
+	return func(o *options) {
+		o.trailingCommas = true
+	}
+}
+
+// WithExtraWhitespace makes the parser skip the given runes between tokens like the
+// space, tab, line feed and carriage return of RFC 8259, e.g. '\v', '\f' or U+00A0.
+// The runes must not be significant in JSON. They are not part of the extracted JSON
+func WithExtraWhitespace(runes ...rune) Option {
+	return func(o *options) {
+		o.extraSpace = append(o.extraSpace, runes...)
+	}
+}
+
+// WithWindowsPathStrings makes a backslash that does not start a valid escape sequence
+// a literal backslash, so that strings such as "C:\Users\x" survive. Valid escapes keep
+// their meaning, so "C:\new" still contains a newline
+func WithWindowsPathStrings(enabled bool) Option {
+	return func(o *options) {
+		o.windowsPaths = enabled
+	}
+}
+
+// WithEscapeHandler sets a handler for escape sequences in strings, such as \x41 from
+// producers that are not RFC 8259 compliant. For each backslash, the handler receives
+// the byte after it and a reader of up to 16 following bytes, and returns
+// the decoded bytes of the sequence after reading the rest of it from r. A nil result
+// with a nil error leaves the sequence to the parser, so the handler may claim only
+// the escapes it knows. An error fails the parse with ErrEscape
+func WithEscapeHandler(handler func(b byte, r *bytes.Reader) ([]byte, error)) Option {
+	return func(o *options) {
+		o.escapeHandler = handler
+	}
+}
+
+// WithImpreciseNumberHandler sets a handler for numbers decoded into interface{} that
+// cannot be represented exactly as float64, such as 9007199254740993. The handler
+// receives the number token and returns the value to store (e.g. json.Number or
+// *big.Int) or an error to abort decoding
+func WithImpreciseNumberHandler(handler func(token string) (interface{}, error)) Option {
+	return func(o *options) {
+		o.impreciseNumberHandler = handler
+	}
+}
+
+// WithNoStdlib makes decoding fully self-contained: values are materialized by the
+// package itself instead of encoding/json. Only *interface{}, *map[string]interface{}
+// and *[]interface{} destinations are supported in this mode
+func WithNoStdlib(enabled bool) Option {
+	return func(o *options) {
+		o.noStdlib = enabled
+	}
+}
+
+// WithIntegerNumbers makes numbers without fraction or exponent decode as int64
+// instead of float64 when the destination is interface{}, including interface{}
+// struct fields, map values and slice elements. Integers beyond int64 stay float64
+func WithIntegerNumbers(enabled bool) Option {
+	return func(o *options) {
+		o.integerNumbers = enabled
+	}
+}
+
+// WithTee copies every byte read from the underlying reader into w, including garbage,
+// so that the exact input seen by a Decoder or UnmarshalReader can be captured.
+// Reads happen in chunks of the buffer size, so w may receive bytes beyond the last decoded value
+func WithTee(w io.Writer) Option {
+	return func(o *options) {
+		o.tee = w
+	}
+}
+
+// WithAllocBudget sets a callback charged with the number of bytes added each time an
+// internal extraction buffer grows. Returning an error aborts the parse with that error,
+// which allows integrating with external memory accounting. Capacity reused from the
+// buffer pool is not charged
+func WithAllocBudget(charge func(n int) error) Option {
+	return func(o *options) {
+		o.allocBudget = charge
+	}
+}
+
+// WithLazyBelowDepth leaves objects and arrays nested deeper than depth as json.RawMessage
+// instead of decoding them. The top-level value is at depth 1. Values are materialized by
+// the package itself as with WithNoStdlib, so the same destination restrictions apply
+func WithLazyBelowDepth(depth int) Option {
+	return func(o *options) {
+		if depth > 0 {
+			o.lazyDepth = depth
+		}
+	}
+}
+
+// WithNumberPaths makes numbers at the given dotted paths, such as "order.amount" or
+// "items.0.price", decode as json.Number while other numbers stay float64. Array
+// elements are addressed by index. Values are materialized by the package itself as
+// with WithNoStdlib, so the same destination restrictions apply
+func WithNumberPaths(paths []string) Option {
+	return func(o *options) {
+		o.numberPaths = make(map[string]bool, len(paths))
+		for _, path := range paths {
+			o.numberPaths[path] = true
+		}
+	}
+}
+
+// WithStrictArrayLength makes decoding fail when a JSON array is decoded into a
+// fixed-size Go array such as [10]int and the lengths differ. By default extra
+// elements are dropped and missing ones are left as zero values, as in encoding/json
+func WithStrictArrayLength(enabled bool) Option {
+	return func(o *options) {
+		o.strictArrayLen = enabled
+	}
+}
+
+// WithValueHook calls hook for every string, number, boolean and null value as it is
+// decoded and stores the returned value instead, e.g. to redact fields in-flight. path
+// holds the object keys and array indices leading to the value and is only valid during
+// the call. Values are materialized by the package itself as with WithNoStdlib, so the
+// same destination restrictions apply
+func WithValueHook(hook func(path []string, value interface{}) interface{}) Option {
+	return func(o *options) {
+		o.valueHook = hook
+	}
+}
+
+// WithDisallowDuplicateKeys makes a key repeated within an object a syntax error
+// reporting the key and its position. Keys are compared after decoding escapes
+func WithDisallowDuplicateKeys() Option {
+	return func(o *options) {
+		o.disallowDupKeys = true
+	}
+}
+
+// WithDuplicateKeysAsArray collects the values of a key repeated within an object
+// into a slice, so {"tag":"a","tag":"b"} decodes as {"tag":["a","b"]}. By default the
+// last value wins. Values are materialized by the package itself as with WithNoStdlib,
+// so the same destination restrictions apply
+func WithDuplicateKeysAsArray(enabled bool) Option {
+	return func(o *options) {
+		o.collectDupKeys = enabled
+	}
+}
+
+// WithAllowScalars makes strings, numbers, booleans and null candidates for extraction
+// in addition to objects and arrays, so that Unmarshal([]byte("noise 42 noise"), &n)
+// yields 42. Decoder also extracts scalars and, since words in surrounding text may start
+// like a literal, keeps searching after a candidate that fails to parse
+func WithAllowScalars(enabled bool) Option {
+	return func(o *options) {
+		o.allowScalars = enabled
+	}
+}
+
+// WithSelection sets which JSON value Unmarshal extracts when several are present
+func WithSelection(selection Selection) Option {
+	return func(o *options) {
+		o.selection = selection
+	}
+}
+
+// WithUseNumber makes numbers decoded into interface{} json.Number instead of float64,
+// so that integers such as 1234567890123456789 keep their exact value
+func WithUseNumber() Option {
+	return func(o *options) {
+		o.useNumber = true
+	}
+}
+
+// WithDisallowUnknownFields makes decoding into a struct fail when the extracted object
+// has a key that matches no exported field, like json.Decoder.DisallowUnknownFields
+func WithDisallowUnknownFields() Option {
+	return func(o *options) {
+		o.disallowUnknown = true
+	}
+}
+
+// WithFirstMatch makes Unmarshal extract the first valid JSON value like Decoder does,
+// instead of the longest one. It is shorthand for WithSelection(First)
+func WithFirstMatch() Option {
+	return WithSelection(First)
+}
+
+// WithKeepSkipped makes a Decoder collect the bytes it skips as garbage while searching
+// for a value, which LastSkipped reports after the value is decoded. The skipped bytes
+// are held in memory until then, so it is off by default
+func WithKeepSkipped() Option {
+	return func(o *options) {
+		o.keepSkipped = true
+	}
+}
+
+// WithDelimiters extracts JSON values only from between the markers start and end,
+// such as "<<<JSON" and "JSON>>>", instead of searching for the longest value. The
+// region between the markers must be a JSON value apart from whitespace, and errors
+// report positions in the whole input. Unmarshal, UnmarshalAt, Extract and Query use
+// the first region; Decode and More go from region to region. Both markers must be non-empty
+func WithDelimiters(start, end string) Option {
+	return func(o *options) {
+		if start != "" && end != "" {
+			o.startMarker = start
+			o.endMarker = end
+		}
+	}
+}
+
+// WithSortKeys makes Normalize write the members of every object, nested ones included,
+// in lexicographic order of their keys, for a deterministic representation suitable for
+// hashing. Members with the same key keep their order
+func WithSortKeys() Option {
+	return func(o *options) {
+		o.sortKeys = true
+	}
+}
+
+// WithEscapeLineSeparators controls whether Normalize and Marshal escape the line and
+// paragraph separators U+2028 and U+2029 in strings as \u2028 and \u2029. They are valid
+// in JSON but end lines in JavaScript before ES2019, so they are escaped by default, as
+// encoding/json does. Parsing accepts them either way
+func WithEscapeLineSeparators(enabled bool) Option {
+	return func(o *options) {
+		o.rawSeparators = !enabled
+	}
+}
+
+// WithStrictBoundaries makes Unmarshal, UnmarshalAt and Extract require the JSON value
+// to be the whole input apart from surrounding whitespace, like json.Unmarshal but with
+// the error positions of this package. Leading or trailing content is a syntax error
+// instead of garbage to skip, and the selection does not apply
+func WithStrictBoundaries() Option {
+	return func(o *options) {
+		o.strictBounds = true
+	}
+}
+
+// allowsFastPath checks if the options permit decoding clean input with encoding/json directly
+func (o options) allowsFastPath() bool {
+	return !o.customLimits() &&
+		!o.materializes() && o.allocBudget == nil && o.escapeHandler == nil && o.startMarker == "" &&
+		!o.disallowDupKeys && // encoding/json accepts duplicate keys
+		!o.strictArrayLen && // the length check walks the value, so it must be validated with maxDepth first
+		o.selection != First // validating the whole input would defeat stopping at the first value
+}
+
+// customLimits checks if the options change the default limits of the parser
+func (o options) customLimits() bool {
+	return o.maxDepth != 1000 || o.bufferSize != 4096 || o.maxStringLen > 0 ||
+		o.maxObjectKeys > 0 || o.maxArrayElems > 0
+}
+
+// startBytes returns the bytes that may start an extracted JSON value
+func (o options) startBytes() string {
+	switch {
+	case o.allowScalars && o.lenientLiterals:
+		return `{["-0123456789tfnTFN`
+	case o.allowScalars:
+		return `{["-0123456789tfn`
+	default:
+		return "{["
+	}
+}
+
+// materializes checks if the options need values materialized by the package itself
+func (o options) materializes() bool {
+	return o.noStdlib || o.lazyDepth > 0 || len(o.numberPaths) > 0 || o.valueHook != nil ||
+		o.collectDupKeys
+}
+
 // applyOptions applies the given options to the default configuration
 func applyOptions(opts ...Option) options {
 	o := defaultOptions()
@@ -1,6 +1,12 @@
 package jsonex
 
 import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -84,3 +90,95 @@ func TestApplyOptionsEmpty(t *testing.T) {
 		t.Errorf("applyOptions() bufferSize = %d, expected 4096", opts.bufferSize)
 	}
 }
+
+func TestWithStrictUTF8(t *testing.T) {
+	opts := defaultOptions()
+	if !opts.strictUTF8 {
+		t.Error("defaultOptions().strictUTF8 = false, expected true")
+	}
+
+	WithStrictUTF8(false)(&opts)
+	if opts.strictUTF8 {
+		t.Error("WithStrictUTF8(false) resulted in strictUTF8 = true")
+	}
+}
+
+func TestWithReplaceInvalidUTF8(t *testing.T) {
+	opts := applyOptions(WithReplaceInvalidUTF8())
+	if opts.strictUTF8 {
+		t.Error("WithReplaceInvalidUTF8() resulted in strictUTF8 = true")
+	}
+
+	var result map[string]string
+	if err := Unmarshal([]byte("log: {\"msg\": \"bad \xff\xfe bytes\"} end"), &result, WithReplaceInvalidUTF8()); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if expected := "bad \uFFFD\uFFFD bytes"; result["msg"] != expected {
+		t.Errorf("Unmarshal = %q, expected %q", result["msg"], expected)
+	}
+}
+
+func TestOptions_ConcurrentSharedSlice(t *testing.T) {
+	var charged atomic.Int64
+	opts := []Option{
+		WithMaxDepth(50),
+		WithBufferSize(1024),
+		WithStrictUTF8(false),
+		WithCSVQuoting(true),
+		WithLargeFileMode(1 << 16),
+		WithValuePerReader(true),
+		WithLenientLiterals(true),
+		WithImpreciseNumberHandler(func(token string) (interface{}, error) {
+			return json.Number(token), nil
+		}),
+		WithNoStdlib(true),
+		WithIntegerNumbers(true),
+		WithTee(io.Discard),
+		WithAllocBudget(func(n int) error {
+			charged.Add(int64(n))
+			return nil
+		}),
+		WithLazyBelowDepth(3),
+		WithSelection(Longest),
+	}
+
+	inputs := []string{
+		`log: {"id": 1, "ok": TRUE, "big": 9007199254740993} trailing [1]`,
+		`noise [1, {"nested": {"deep": [1, 2]}}] {"short": 1}`,
+		`{"s": "` + strings.Repeat("x", 5000) + `", "none": None}`,
+		"{\"bad\": \"\xff\"} and {\"a\": [1.5, -2]}",
+	}
+
+	// Results of sequential runs are the reference for concurrent ones
+	expected := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		if err := Unmarshal([]byte(input), &expected[i], opts...); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", input, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				i := n % len(inputs)
+				var result interface{}
+				if err := Unmarshal([]byte(inputs[i]), &result, opts...); err != nil {
+					t.Errorf("Unmarshal(%q) failed: %v", inputs[i], err)
+					return
+				}
+				if !reflect.DeepEqual(result, expected[i]) {
+					t.Errorf("Unmarshal(%q) = %#v, expected %#v", inputs[i], result, expected[i])
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if charged.Load() == 0 {
+		t.Error("Allocation budget was never charged")
+	}
+}
@@ -1,7 +1,13 @@
 package jsonex
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // parseState represents the current state of the JSON parser (unexported)
@@ -20,18 +26,56 @@ const (
 	stateEnd
 )
 
+// String returns a human readable description of the parse state
+func (s parseState) String() string {
+	switch s {
+	case stateValue:
+		return "searching for value"
+	case stateObjectStart:
+		return "in object, expecting key or '}'"
+	case stateObjectKey:
+		return "in object, expecting key"
+	case stateObjectColon:
+		return "in object, expecting ':'"
+	case stateObjectValue:
+		return "in object, expecting value"
+	case stateObjectComma:
+		return "in object, expecting ',' or '}'"
+	case stateArrayStart:
+		return "in array, expecting value or ']'"
+	case stateArrayValue:
+		return "in array, expecting value"
+	case stateArrayComma:
+		return "in array, expecting ',' or ']'"
+	case stateEnd:
+		return "value complete"
+	default:
+		return "unknown state"
+	}
+}
+
 // parser handles JSON syntax parsing and validation (unexported)
 type parser struct {
 	scanner *scanner
 	options options
 	depth   int
 	state   parseState
+
+	stringStart int64 // start of the contents of the last string entered
+
+	// values completed inside a failed value, used by parseLongest and parseEach
+	nestedSpans   [][2]int64 // outermost values completed inside the value, if collectNested
+	collectNested bool       // record nestedSpans
 }
 
 // newParser creates a new parser
 func newParser(reader io.Reader, opts options) *parser {
+	scanner := newScanner(reader, opts.bufferSize)
+	scanner.starts = opts.startBytes()
+	scanner.comments = opts.allowComments
+	scanner.extraSpace = opts.extraSpace
 	return &parser{
-		scanner: newScanner(reader, opts.bufferSize),
+		scanner: scanner,
 		options: opts,
 		depth:   0,
 		state:   stateValue,
@@ -46,79 +90,617 @@ func (p *parser) parseNext() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	return p.parseFrom(startByte)
+}
 
+// parseFrom extracts the JSON object or array starting with startByte, which has
+// been found but not consumed. On failure the input after startByte is kept for the next search
+func (p *parser) parseFrom(startByte byte) ([]byte, error) {
 	// Reset parser state
 	p.depth = 0
 	p.state = stateValue
+	p.nestedSpans = p.nestedSpans[:0]
 
 	// Create buffer to collect the JSON
-	buf := getBuffer()
+	buf := p.getValueBuffer()
 	defer putBuffer(buf)
 
+	// Record consumed bytes so that a failed value can be rescanned
+	p.scanner.mark()
+
 	// Start parsing from the found position
-	return p.parseValue(startByte, buf)
+	result, err := p.parseValue(startByte, buf)
+	if err == nil {
+		err = buf.err
+	}
+	if err != nil {
+		// A truncated value must not look like the clean end of the stream
+		if err == io.EOF {
+			if p.scanner.atBoundary {
+				err = newEOFError(p.scanner.position(), "value crosses reader boundary")
+			} else {
+				err = newEOFError(p.scanner.position(), "unexpected end of input in JSON value")
+			}
+		}
+
+		// Recover by resuming the next search right after the failed start byte
+		p.scanner.rewind(1)
+		return nil, err
+	}
+	p.scanner.unmark()
+	p.state = stateEnd
+
+	// The result aliases buf, which is returned to the pool on return
+	owned := make([]byte, len(result))
+	copy(owned, result)
+	return owned, nil
+}
+
+// getBuffer gets a pooled buffer that charges its growth to the allocation budget
+func (p *parser) getBuffer() *buffer {
+	buf := getBuffer()
+	buf.budget = p.options.allocBudget
+	return buf
+}
+
+// getValueBuffer is like getBuffer but for a whole value, so it starts with at least
+// the configured buffer size. Buffers of nested values start small, as every level has one
+func (p *parser) getValueBuffer() *buffer {
+	buf := p.getBuffer()
+	buf.grow(p.options.bufferSize)
+	return buf
 }
 
 // parseLongest finds and extracts the longest valid JSON from byte data
 // This is used by the Unmarshal function for batch processing
-func parseLongest(data []byte, opts options) ([]byte, error) {
+// start and end delimit the source bytes of the extracted JSON in data
+//
+// The data is scanned forward once. The parse from a start byte that an earlier candidate
+// parsed as an element is known: it is a value nested in the candidate, so shorter than
+// an extracted one, or it fails at the same byte as a failed one. Only the other start
+// bytes are parsed, including those inside the strings and comments of a candidate, as a
+// value starting there may end after them
+func parseLongest(data []byte, opts options) (jsonBytes []byte, start, end int, err error) {
 	var longestJSON []byte
 	var bestLength int
-	var hasCustomOptions = opts.maxDepth != 1000 || opts.bufferSize != 4096
+	var unicodeErr error
+	var hasCustomOptions = opts.customLimits()
+	starts := opts.startBytes()
+
+	// Reject inputs without any candidate start without walking them byte by byte
+	if limit := opts.maxGarbage; limit > 0 && bytes.IndexAny(data[:min(limit+1, len(data))], starts) < 0 {
+		if len(data) <= limit {
+			return nil, 0, 0, newNoJSONError(position{}, "no valid JSON found")
+		}
+		return nil, 0, 0, errGarbagePrefix(limit)
+	}
+	if bytes.IndexAny(data, starts) < 0 {
+		return nil, 0, 0, newNoJSONError(position{}, "no valid JSON found")
+	}
+
+	// Track the absolute position of each candidate for error reporting
+	base := position{line: 1, column: 1}
 
-	// Try parsing from each potential JSON start position
+	// One parser is reset for every candidate
+	parser := newBytesParser(data, base, opts)
+	parser.collectNested = true
+	defer parser.scanner.release()
+
+	consider := func(jsonData []byte, from, to int) {
+		if len(jsonData) > bestLength {
+			longestJSON = jsonData
+			bestLength = len(jsonData)
+			start, end = from, to
+		}
+	}
+
+	var covered coverage
 	for i := 0; i < len(data); i++ {
-		if data[i] == '{' || data[i] == '[' {
+		next := bytes.IndexAny(data[i:], starts)
+		if next < 0 {
+			break
+		}
+		base = base.advanceAll(data[i : i+next])
+		i += next
+		if opts.maxGarbage > 0 && i > opts.maxGarbage {
+			break
+		}
+
+		nestedEnd, known := covered.at(i)
+		if known && nestedEnd > 0 {
+			// A value completed inside a failed one, which is the longest starting here
+			if jsonData, _, err := parser.tryParseFromPosition(data[i:nestedEnd], base); err == nil {
+				consider(jsonData, i, nestedEnd)
+			}
+		} else if !known {
 			// Try to parse JSON starting from this position
-			jsonData, length, err := tryParseFromPosition(data[i:], opts)
-			if err == nil && length > bestLength {
-				longestJSON = make([]byte, length)
-				copy(longestJSON, jsonData)
-				bestLength = length
-			} else if err != nil {
+			jsonData, consumed, err := parser.tryParseFromPosition(data[i:], base)
+			if err == nil {
+				consider(jsonData, i, i+consumed)
+				covered.add(data, i, i+consumed, opts.allowComments, nil)
+			} else {
 				// If we have custom options (especially depth limits) and encounter depth errors,
 				// return the error immediately to enforce limits strictly
-				if hasCustomOptions && isDepthError(err) {
-					return nil, err
+				if (hasCustomOptions && isLimitError(err)) || isAbortError(err) {
+					return nil, 0, 0, err
+				}
+				if unicodeErr == nil && isUnicodeError(err) {
+					unicodeErr = err
 				}
+
+				covered.add(data, i, failedAt(err, i), opts.allowComments, parser.nested())
 			}
 		}
+		base = base.advance(data[i])
 	}
 
 	// If we found valid JSON, return it
 	if longestJSON != nil {
-		return longestJSON, nil
+		return longestJSON, start, end, nil
 	}
 
-	return nil, newInvalidJSONError(position{}, "no valid JSON found")
+	// Report rejected UTF-8 rather than a generic error when it was the only obstacle
+	if unicodeErr != nil {
+		return nil, 0, 0, unicodeErr
+	}
+
+	return nil, 0, 0, newNoValidJSONError(position{}, "no valid JSON found")
 }
 
-// isDepthError checks if an error is related to depth limits
-func isDepthError(err error) bool {
+// failedAt returns the offset where the candidate starting at start failed with err
+func failedAt(err error, start int) int {
+	if jsonErr, ok := err.(*Error); ok {
+		return max(start+1, int(jsonErr.Position.Offset))
+	}
+	return start + 1
+}
+
+// coverage holds the candidates parsed so far, to tell whether the parse from a later
+// start byte is known
+type coverage struct {
+	spans []coveredSpan
+}
+
+// coveredSpan is a candidate that parsed the start bytes after start and before end as
+// the start of elements, except inside its strings and comments
+type coveredSpan struct {
+	end    int
+	holes  [][2]int // contents of strings and comments, in order
+	nested [][2]int // outermost values completed inside a failed candidate, in order
+}
+
+// add records the candidate parsed from data[start:end]
+func (c *coverage) add(data []byte, start, end int, comments bool, nested [][2]int) {
+	c.spans = append(c.spans, coveredSpan{
+		end:    end,
+		holes:  skippedSpans(data, start, end, comments),
+		nested: nested,
+	})
+}
+
+// at reports whether the parse from the start byte at i is known. A known parse completes
+// the nested value ending at nestedEnd if it is not 0, and fails otherwise. Candidates
+// must be added and queried in increasing order of their offsets
+func (c *coverage) at(i int) (nestedEnd int, known bool) {
+	spans := c.spans[:0]
+	for _, span := range c.spans {
+		if span.end > i {
+			spans = append(spans, span)
+		}
+	}
+	c.spans = spans
+
+	for k := range c.spans {
+		span := &c.spans[k]
+		for len(span.holes) > 0 && span.holes[0][1] <= i {
+			span.holes = span.holes[1:]
+		}
+		if len(span.holes) > 0 && span.holes[0][0] <= i {
+			continue
+		}
+
+		for len(span.nested) > 0 && span.nested[0][0] < i {
+			span.nested = span.nested[1:]
+		}
+		if len(span.nested) > 0 && span.nested[0][0] == i {
+			return span.nested[0][1], true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// skippedSpans returns the spans of the contents of strings and comments in
+// data[start:end], which starts outside of both and is valid JSON up to end. The span
+// of a string includes its closing quote, which opens a string for a value starting
+// inside it
+func skippedSpans(data []byte, start, end int, comments bool) [][2]int {
+	var spans [][2]int
+	for i := start; i < end; i++ {
+		switch {
+		case data[i] == '"':
+			from := i + 1
+			for i = from; i < end && data[i] != '"'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			spans = append(spans, [2]int{from, min(i+1, end)})
+		case data[i] == '/' && comments && i+1 < end && (data[i+1] == '/' || data[i+1] == '*'):
+			from := i
+			if data[i+1] == '/' {
+				if n := bytes.IndexByte(data[i:end], '\n'); n >= 0 {
+					i += n
+				} else {
+					i = end
+				}
+			} else if n := bytes.Index(data[i+2:end], []byte("*/")); n >= 0 {
+				i += n + 3
+			} else {
+				i = end
+			}
+			spans = append(spans, [2]int{from, min(i+1, end)})
+		}
+	}
+	return spans
+}
+
+// parseSelected extracts the JSON value chosen by the selection option
+func parseSelected(data []byte, opts options) (jsonBytes []byte, start, end int, err error) {
+	if opts.startMarker != "" {
+		return parseDelimited(data, opts)
+	}
+	if opts.strictBounds {
+		return parseWhole(data, position{line: 1, column: 1}, opts)
+	}
+	switch opts.selection {
+	case First:
+		return parseSequential(data, opts, true)
+	case Last:
+		return parseSequential(data, opts, false)
+	default:
+		return parseLongest(data, opts)
+	}
+}
+
+// noValidJSONError returns the error for data in which no value could be extracted,
+// which matches ErrNoJSON if no value even starts in data
+func noValidJSONError(data []byte, opts options) *Error {
+	if bytes.IndexAny(data, opts.startBytes()) < 0 {
+		return newNoJSONError(position{}, "no valid JSON found")
+	}
+	return newNoValidJSONError(position{}, "no valid JSON found")
+}
+
+// parseDelimited extracts the JSON value between the first pair of WithDelimiters
+// markers in data. The region between them must be the value apart from whitespace
+func parseDelimited(data []byte, opts options) (jsonBytes []byte, start, end int, err error) {
+	from := bytes.Index(data, []byte(opts.startMarker))
+	if from < 0 {
+		return nil, 0, 0, newNoJSONError(position{}, "start delimiter not found")
+	}
+	from += len(opts.startMarker)
+	base := position{line: 1, column: 1}.advanceAll(data[:from])
+
+	to := bytes.Index(data[from:], []byte(opts.endMarker))
+	if to < 0 {
+		return nil, 0, 0, newEOFError(base.advanceAll(data[from:]), "end delimiter not found")
+	}
+
+	jsonBytes, start, end, err = parseWhole(data[from:from+to], base, opts)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return jsonBytes, from + start, from + end, nil
+}
+
+// parseDelimitedNext extracts the JSON value between the next pair of WithDelimiters
+// markers in the stream. It returns io.EOF if no start marker remains
+func (p *parser) parseDelimitedNext() ([]byte, error) {
+	s := p.scanner
+	start := []byte(p.options.startMarker)
+	if err := s.skipTo(start); err != nil {
+		return nil, err
+	}
+	s.skipGarbage(s.buffer[s.pos : s.pos+len(start)])
+
+	if err := s.skipWhitespace(); err == io.EOF {
+		return nil, newEOFError(s.position(), "unexpected end of input after start delimiter")
+	} else if err != nil {
+		return nil, err
+	}
+	b, err := s.peek()
+	if err != nil {
+		return nil, err
+	}
+	if strings.IndexByte(p.options.startBytes(), b) < 0 {
+		return nil, newSyntaxError(s.position(), "expected JSON value after start delimiter, found "+quoteByte(b), s.snippet())
+	}
+
+	jsonBytes, err := p.parseFrom(b)
+	if err != nil {
+		return nil, err
+	}
+
+	end := p.options.endMarker
+	if err := s.skipWhitespace(); err != nil && err != io.EOF {
+		return nil, err
+	}
+	next, err := s.peekBytes(len(end))
+	if err != nil {
+		return nil, err
+	}
+	if string(next) != end {
+		if len(next) < len(end) && s.eof {
+			return nil, newEOFError(s.position(), "end delimiter not found")
+		}
+		return nil, newSyntaxError(s.position(), "expected end delimiter "+strconv.Quote(end), s.snippet())
+	}
+	s.skipGarbage(next)
+	return jsonBytes, nil
+}
+
+// parseWhole extracts the JSON value that makes up all of data apart from surrounding
+// whitespace, as required by WithStrictBoundaries and within WithDelimiters markers.
+// base is the position of data[0] in the original input
+func parseWhole(data []byte, base position, opts options) (jsonBytes []byte, start, end int, err error) {
+	parser := newBytesParser(data, base, opts)
+	defer parser.scanner.release()
+	s := parser.scanner
+
+	if err := s.skipWhitespace(); err == io.EOF {
+		return nil, 0, 0, newNoJSONError(s.position(), "no valid JSON found")
+	} else if err != nil {
+		return nil, 0, 0, err
+	}
+	b, err := s.peek()
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if strings.IndexByte(opts.startBytes(), b) < 0 {
+		return nil, 0, 0, newSyntaxError(s.position(), "unexpected content before JSON value: "+quoteByte(b), s.snippet())
+	}
+
+	start = int(s.offset - base.offset)
+	if jsonBytes, err = parser.parseFrom(b); err != nil {
+		return nil, 0, 0, err
+	}
+	end = int(s.offset - base.offset)
+
+	if err := s.skipWhitespace(); err != io.EOF {
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		b, _ := s.peek()
+		return nil, 0, 0, newSyntaxError(s.position(), "unexpected content after JSON value: "+quoteByte(b), s.snippet())
+	}
+	return jsonBytes, start, end, nil
+}
+
+// parseSequential extracts non-overlapping JSON values from left to right, resuming
+// after the end of each value, and returns the first or the last one
+func parseSequential(data []byte, opts options, first bool) (jsonBytes []byte, start, end int, err error) {
+	err = parseEach(data, opts, func(jsonData []byte, s, e int) bool {
+		jsonBytes, start, end = jsonData, s, e
+		return !first
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if jsonBytes == nil {
+		return nil, 0, 0, noValidJSONError(data, opts)
+	}
+	return jsonBytes, start, end, nil
+}
+
+// parseEach extracts non-overlapping JSON values from left to right, resuming after
+// the end of each value, and calls yield with each value and its source bytes
+// data[start:end] until yield returns false
+//
+// As in parseLongest, the start bytes that a failed candidate parsed as elements are not
+// parsed again: they start one of the values completed inside it or fail the same way
+func parseEach(data []byte, opts options, yield func(jsonBytes []byte, start, end int) bool) error {
+	var hasCustomOptions = opts.customLimits()
+	starts := opts.startBytes()
+
+	base := position{line: 1, column: 1}
+	parser := newBytesParser(data, base, opts)
+	parser.collectNested = true
+	defer parser.scanner.release()
+
+	// Only the first value is bounded by WithMaxGarbagePrefix
+	limit := opts.maxGarbage
+
+	var covered coverage
+	for i := 0; i < len(data); {
+		next := bytes.IndexAny(data[i:], starts)
+		if next < 0 {
+			break
+		}
+		base = base.advanceAll(data[i : i+next])
+		i += next
+		if limit > 0 && i > limit {
+			return errGarbagePrefix(limit)
+		}
+
+		end, known := covered.at(i)
+		if !known {
+			end = len(data)
+		}
+		if known && end == 0 {
+			base = base.advance(data[i])
+			i++
+			continue
+		}
+
+		jsonData, consumed, parseErr := parser.tryParseFromPosition(data[i:end], base)
+		if parseErr != nil {
+			if (hasCustomOptions && isLimitError(parseErr)) || isAbortError(parseErr) {
+				return parseErr
+			}
+			if !known {
+				covered.add(data, i, failedAt(parseErr, i), opts.allowComments, parser.nested())
+			}
+			base = base.advance(data[i])
+			i++
+			continue
+		}
+
+		if !yield(jsonData, i, i+consumed) {
+			return nil
+		}
+		limit = 0
+
+		// Resume after the extracted value
+		base = base.advanceAll(data[i : i+consumed])
+		i += consumed
+	}
+	return nil
+}
+
+// parseLongestWindowed applies parseLongest to overlapping windows of the stream
+// and returns the longest valid JSON found in any window
+func parseLongestWindowed(r io.Reader, opts options) ([]byte, error) {
+	window := make([]byte, 0, opts.windowSize)
+	step := max(opts.windowSize/2, 1)
+
+	var longestJSON []byte
+	var lastErr error = newNoJSONError(position{}, "no valid JSON found")
+
+	// WithMaxGarbagePrefix bounds the offset of the first value in the whole stream
+	limit := opts.maxGarbage
+	var windowStart int
+
+	for {
+		n, err := io.ReadFull(r, window[len(window):cap(window)])
+		window = window[:len(window)+n]
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+
+		// The remaining bytes were already examined as part of the previous window
+		if n == 0 && err != nil {
+			break
+		}
+
+		windowOpts := opts
+		if limit > 0 {
+			windowOpts.maxGarbage = limit - windowStart
+		}
+		jsonData, _, _, parseErr := parseLongest(window, windowOpts)
+		if parseErr == nil {
+			if len(jsonData) > len(longestJSON) {
+				longestJSON = jsonData
+			}
+		} else if isLimitError(parseErr) || isAbortError(parseErr) {
+			return nil, parseErr
+		} else if !errors.Is(parseErr, ErrNoJSON) {
+			// A window without JSON does not hide a failed value in another one
+			lastErr = parseErr
+		}
+
+		if err != nil {
+			break
+		}
+
+		// Slide the window forward, keeping the second half
+		n = copy(window, window[step:])
+		window = window[:n]
+		windowStart += step
+
+		// No value may start in the next window
+		if limit > 0 && windowStart >= limit {
+			if longestJSON == nil {
+				lastErr = errGarbagePrefix(limit)
+			}
+			break
+		}
+	}
+
+	if longestJSON != nil {
+		return longestJSON, nil
+	}
+	return nil, lastErr
+}
+
+// isLimitError checks if an error is related to depth, string length or size limits
+func isLimitError(err error) bool {
 	if jsonErr, ok := err.(*Error); ok {
 		return jsonErr.Type == ErrSyntax &&
-			(jsonErr.Message == "maximum nesting depth exceeded")
+			(jsonErr.Message == "maximum nesting depth exceeded" ||
+				jsonErr.Message == "maximum string length exceeded" ||
+				jsonErr.Message == "maximum object keys exceeded" ||
+				jsonErr.Message == "maximum array elements exceeded")
 	}
 	return false
 }
 
-// tryParseFromPosition attempts to parse JSON from a specific position
-func tryParseFromPosition(data []byte, opts options) ([]byte, int, error) {
+// isAbortError checks if an error comes from outside the parser, such as an
+// allocation budget, and must stop the search for further candidates
+func isAbortError(err error) bool {
+	_, ok := err.(*Error)
+	return !ok && err != io.EOF
+}
+
+// isCandidateError checks if an error only rejects the current candidate, so that
+// the search can continue with the next start byte. Input errors, such as an
+// exceeded input size, are not
+func isCandidateError(err error) bool {
+	jsonErr, ok := err.(*Error)
+	return ok && jsonErr.Type != ErrEOF && jsonErr.Type != ErrInvalidJSON
+}
+
+// isUnicodeError checks if an error is a UTF-8 validation error
+func isUnicodeError(err error) bool {
+	if jsonErr, ok := err.(*Error); ok {
+		return jsonErr.Type == ErrUnicode
+	}
+	return false
+}
+
+// tryParseFromPosition attempts to parse JSON from a specific position, reusing a
+// parser created by newBytesParser for every candidate
+// base is the position of data[0] in the original input
+// It returns the extracted JSON and the number of source bytes it was parsed from
+func (p *parser) tryParseFromPosition(data []byte, base position) ([]byte, int, error) {
 	if len(data) == 0 {
 		return nil, 0, newEOFError(position{}, "empty data")
 	}
 
-	// Create a temporary scanner for this data
-	reader := &bytesReader{data: data, pos: 0}
-	parser := newParser(reader, opts)
+	p.resetFor(data, base)
 
 	// Try to parse
-	result, err := parser.parseNext()
+	result, err := p.parseNext()
 	if err != nil {
 		return nil, 0, err
 	}
 
-	return result, len(result), nil
+	return result, int(p.scanner.offset - base.offset), nil
+}
+
+// resetFor makes a parser created by newBytesParser read data instead, where base is
+// the position of data[0] in the original input. The read buffer is kept
+func (p *parser) resetFor(data []byte, base position) {
+	reader := p.scanner.reader.(*bytesReader)
+	reader.data = data
+	reader.pos = 0
+	p.scanner.reset(reader, p.options.bufferSize)
+	p.scanner.setPosition(base)
+
+	p.depth = 0
+	p.state = stateValue
+	p.nestedSpans = p.nestedSpans[:0]
+}
+
+// newBytesParser creates a parser for data, where base is the position of data[0]
+// in the original input
+func newBytesParser(data []byte, base position, opts options) *parser {
+	// Buffers larger than data would never be filled
+	opts.bufferSize = min(opts.bufferSize, max(len(data), 1))
+	parser := newParser(&bytesReader{data: data, pos: 0}, opts)
+	parser.scanner.setPosition(base)
+	return parser
 }
 
 // bytesReader implements io.Reader for byte slices
@@ -142,7 +724,7 @@ func (r *bytesReader) Read(p []byte) (int, error) {
 	return n, nil
 }
 
-// parseValue parses a JSON value (object or array)
+// parseValue parses a JSON value (object or array, or a scalar with WithAllowScalars)
 func (p *parser) parseValue(startByte byte, buf *buffer) ([]byte, error) {
 	switch startByte {
 	case '{':
@@ -150,8 +732,19 @@ func (p *parser) parseValue(startByte byte, buf *buffer) ([]byte, error) {
 	case '[':
 		return p.parseArray(buf)
 	default:
-		return nil, newSyntaxError(p.scanner.position(), "expected '{' or '['")
+		if p.options.allowScalars {
+			return p.parseScalar(buf)
+		}
+		return nil, newSyntaxError(p.scanner.position(), "expected '{' or '[', found "+quoteByte(startByte), p.scanner.snippet())
+	}
+}
+
+// parseScalar parses a top-level string, number, boolean or null
+func (p *parser) parseScalar(buf *buffer) ([]byte, error) {
+	if err := p.parseElement(buf); err != nil {
+		return nil, err
 	}
+	return buf.bytes(), nil
 }
 
 // parseObject parses a JSON object
@@ -166,13 +759,15 @@ func (p *parser) parseObject(buf *buffer) ([]byte, error) {
 	buf.writeByte('{')
 
 	// Consume the opening brace
+	pos := p.scanner.position()
 	b, err := p.scanner.next()
 	if err != nil {
 		return nil, err
 	}
 	if b != '{' {
-		return nil, newSyntaxError(p.scanner.position(), "expected '{'")
+		return nil, newSyntaxError(pos, "expected '{'")
 	}
+	p.state = stateObjectStart
 
 	// Skip whitespace
 	if err := p.scanner.skipWhitespace(); err != nil {
@@ -192,15 +787,27 @@ func (p *parser) parseObject(buf *buffer) ([]byte, error) {
 		return buf.bytes(), nil
 	}
 
+	// Keys seen in this object, tracked only to reject duplicates
+	var keys map[string]bool
+	if p.options.disallowDupKeys {
+		keys = make(map[string]bool)
+	}
+
 	// Parse object content
-	first := true
+	count := 0
 	for {
-		if !first {
+		if buf.err != nil {
+			return nil, buf.err
+		}
+
+		if count > 0 {
 			// Expect comma or closing brace
+			p.state = stateObjectComma
 			if err := p.scanner.skipWhitespace(); err != nil {
 				return nil, err
 			}
 
+			pos := p.scanner.position()
 			b, err := p.scanner.next()
 			if err != nil {
 				return nil, err
@@ -210,15 +817,24 @@ func (p *parser) parseObject(buf *buffer) ([]byte, error) {
 				buf.writeByte('}')
 				return buf.bytes(), nil
 			} else if b == ',' {
+				if closed, err := p.closesAfterComma('}'); err != nil {
+					return nil, err
+				} else if closed {
+					buf.writeByte('}')
+					return buf.bytes(), nil
+				}
 				buf.writeByte(',')
 			} else {
-				return nil, newSyntaxError(p.scanner.position(), "expected ',' or '}'")
+				return nil, newSyntaxError(pos, "expected ',' or '}', found "+quoteByte(b), p.scanner.snippet())
 			}
 		}
-		first = false
+		count++
+		if err := p.checkCount(count, p.options.maxObjectKeys, "maximum object keys exceeded"); err != nil {
+			return nil, err
+		}
 
 		// Parse key-value pair
-		if err := p.parseKeyValuePair(buf); err != nil {
+		if err := p.parseKeyValuePair(buf, keys); err != nil {
 			return nil, err
 		}
 	}
@@ -236,13 +852,15 @@ func (p *parser) parseArray(buf *buffer) ([]byte, error) {
 	buf.writeByte('[')
 
 	// Consume the opening bracket
+	pos := p.scanner.position()
 	b, err := p.scanner.next()
 	if err != nil {
 		return nil, err
 	}
 	if b != '[' {
-		return nil, newSyntaxError(p.scanner.position(), "expected '['")
+		return nil, newSyntaxError(pos, "expected '['")
 	}
+	p.state = stateArrayStart
 
 	// Skip whitespace
 	if err := p.scanner.skipWhitespace(); err != nil {
@@ -263,14 +881,20 @@ func (p *parser) parseArray(buf *buffer) ([]byte, error) {
 	}
 
 	// Parse array content
-	first := true
+	count := 0
 	for {
-		if !first {
+		if buf.err != nil {
+			return nil, buf.err
+		}
+
+		if count > 0 {
 			// Expect comma or closing bracket
+			p.state = stateArrayComma
 			if err := p.scanner.skipWhitespace(); err != nil {
 				return nil, err
 			}
 
+			pos := p.scanner.position()
 			b, err := p.scanner.next()
 			if err != nil {
 				return nil, err
@@ -280,48 +904,95 @@ func (p *parser) parseArray(buf *buffer) ([]byte, error) {
 				buf.writeByte(']')
 				return buf.bytes(), nil
 			} else if b == ',' {
+				if closed, err := p.closesAfterComma(']'); err != nil {
+					return nil, err
+				} else if closed {
+					buf.writeByte(']')
+					return buf.bytes(), nil
+				}
 				buf.writeByte(',')
 			} else {
-				return nil, newSyntaxError(p.scanner.position(), "expected ',' or ']'")
+				return nil, newSyntaxError(pos, "expected ',' or ']', found "+quoteByte(b), p.scanner.snippet())
 			}
 		}
-		first = false
+		count++
+		if err := p.checkCount(count, p.options.maxArrayElems, "maximum array elements exceeded"); err != nil {
+			return nil, err
+		}
 
 		// Parse array element
+		p.state = stateArrayValue
 		if err := p.parseElement(buf); err != nil {
 			return nil, err
 		}
 	}
 }
 
+// closesAfterComma consumes closing if it follows the comma just consumed and
+// trailing commas are allowed, and reports whether it did
+func (p *parser) closesAfterComma(closing byte) (bool, error) {
+	if !p.options.trailingCommas {
+		return false, nil
+	}
+	if err := p.scanner.skipWhitespace(); err != nil {
+		return false, err
+	}
+	if b, err := p.scanner.peek(); err != nil || b != closing {
+		return false, err
+	}
+	_, err := p.scanner.next()
+	return err == nil, err
+}
+
 // parseKeyValuePair parses a key-value pair in an object
-func (p *parser) parseKeyValuePair(buf *buffer) error {
+// If keys is not nil, the key must not be in it and is added to it
+func (p *parser) parseKeyValuePair(buf *buffer, keys map[string]bool) error {
+	p.state = stateObjectKey
+
 	// Skip whitespace before key
 	if err := p.scanner.skipWhitespace(); err != nil {
 		return err
 	}
 
 	// Parse key (must be a string)
+	keyPos := p.scanner.position()
+	keyStart := buf.len()
 	if err := p.parseString(buf); err != nil {
 		return err
 	}
+	if p.options.allowScalars {
+		p.recordNested(keyPos.offset)
+	}
+	if keys != nil {
+		key, err := processEscape(buf.slice(keyStart+1, buf.len()-1))
+		if err != nil {
+			return escapeErrorAt(err, keyPos)
+		}
+		if keys[string(key)] {
+			return newSyntaxError(keyPos, fmt.Sprintf("duplicate key %q", key))
+		}
+		keys[string(key)] = true
+	}
 
 	// Skip whitespace before colon
+	p.state = stateObjectColon
 	if err := p.scanner.skipWhitespace(); err != nil {
 		return err
 	}
 
 	// Expect colon
+	pos := p.scanner.position()
 	b, err := p.scanner.next()
 	if err != nil {
 		return err
 	}
 	if b != ':' {
-		return newSyntaxError(p.scanner.position(), "expected ':'")
+		return newSyntaxError(pos, "expected ':', found "+quoteByte(b), p.scanner.snippet())
 	}
 	buf.writeByte(':')
 
 	// Skip whitespace after colon
+	p.state = stateObjectValue
 	if err := p.scanner.skipWhitespace(); err != nil {
 		return err
 	}
@@ -341,10 +1012,51 @@ func (p *parser) parseElement(buf *buffer) error {
 		return err
 	}
 
+	start := p.scanner.offset
+	if err := p.parseElementFrom(b, buf); err != nil {
+		return err
+	}
+
+	// Remember nested values that would also be extracted on their own
+	if b == '{' || b == '[' || p.options.allowScalars {
+		p.recordNested(start)
+	}
+	return nil
+}
+
+// recordNested remembers the value from start to the current offset among the outermost
+// values completed so far if collectNested is set
+func (p *parser) recordNested(start int64) {
+	if !p.collectNested {
+		return
+	}
+
+	// Values completed before inside this one are part of it
+	for len(p.nestedSpans) > 0 && p.nestedSpans[len(p.nestedSpans)-1][0] >= start {
+		p.nestedSpans = p.nestedSpans[:len(p.nestedSpans)-1]
+	}
+	p.nestedSpans = append(p.nestedSpans, [2]int64{start, p.scanner.offset})
+}
+
+// nested returns a copy of the outermost values completed inside the last value
+func (p *parser) nested() [][2]int {
+	nested := make([][2]int, len(p.nestedSpans))
+	for k, span := range p.nestedSpans {
+		nested[k] = [2]int{int(span[0]), int(span[1])}
+	}
+	return nested
+}
+
+// parseElementFrom parses any JSON element starting with b, which has been peeked
+func (p *parser) parseElementFrom(b byte, buf *buffer) error {
+	if p.options.lenientLiterals && isLiteralStart(b) {
+		return p.parseLenientLiteral(buf)
+	}
+
 	switch b {
 	case '{':
 		// Nested object
-		nestedBuf := getBuffer()
+		nestedBuf := p.getBuffer()
 		defer putBuffer(nestedBuf)
 		objBytes, err := p.parseObject(nestedBuf)
 		if err != nil {
@@ -354,7 +1066,7 @@ func (p *parser) parseElement(buf *buffer) error {
 		return nil
 	case '[':
 		// Nested array
-		nestedBuf := getBuffer()
+		nestedBuf := p.getBuffer()
 		defer putBuffer(nestedBuf)
 		arrBytes, err := p.parseArray(nestedBuf)
 		if err != nil {
@@ -376,8 +1088,42 @@ func (p *parser) parseElement(buf *buffer) error {
 			// Number
 			return p.parseNumber(buf)
 		}
-		return newSyntaxError(p.scanner.position(), "unexpected character")
+		return newSyntaxError(p.scanner.position(), "unexpected character "+quoteByte(b), p.scanner.snippet())
+	}
+}
+
+// parseUnicodeEscape copies a \uXXXX escape sequence whose "\u" has been consumed
+// Unicode escapes are preserved as-is and decoded by the final decoding step
+func (p *parser) parseUnicodeEscape(buf *buffer) error {
+	var digits [4]byte
+	for i := range digits {
+		if p.options.windowsPaths {
+			hexByte, err := p.scanner.peek()
+			if err != nil {
+				return err
+			}
+			if !isHexDigit(hexByte) {
+				// Not an escape, as in C:\users: keep the backslash and the bytes read so far
+				buf.write([]byte(`\\u`))
+				buf.write(digits[:i])
+				return nil
+			}
+		}
+
+		pos := p.scanner.position()
+		hexByte, err := p.scanner.next()
+		if err != nil {
+			return err
+		}
+		if !isHexDigit(hexByte) {
+			return newEscapeError(pos, "invalid hex digit in unicode escape")
+		}
+		digits[i] = hexByte
 	}
+
+	buf.write([]byte(`\u`))
+	buf.write(digits[:])
+	return nil
 }
 
 // parseString parses a JSON string
@@ -385,15 +1131,22 @@ func (p *parser) parseString(buf *buffer) error {
 	buf.writeByte('"')
 
 	// Consume opening quote
+	pos := p.scanner.position()
 	b, err := p.scanner.next()
 	if err != nil {
 		return err
 	}
 	if b != '"' {
-		return newSyntaxError(p.scanner.position(), "expected '\"'")
+		return newSyntaxError(pos, "expected '\"', found "+quoteByte(b), p.scanner.snippet())
 	}
+	p.stringStart = p.scanner.offset
 
 	for {
+		if buf.err != nil {
+			return buf.err
+		}
+
+		pos := p.scanner.position()
 		b, err := p.scanner.next()
 		if err != nil {
 			return err
@@ -413,10 +1166,29 @@ func (p *parser) parseString(buf *buffer) error {
 
 		if b == '\\' {
 			// Escape sequence - decode according to RFC 8259
-			nextByte, err := p.scanner.next()
+			escapePos := p.scanner.position()
+			nextByte, err := p.scanner.peek()
 			if err != nil {
 				return err
 			}
+			if p.options.escapeHandler != nil {
+				if claimed, err := p.handleEscape(escapePos, buf); err != nil {
+					return err
+				} else if claimed {
+					if err := p.checkStringLength(pos); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			if p.options.windowsPaths && !isEscapeChar(nextByte) {
+				// Keep the backslash of paths such as C:\Users and parse the next byte as content
+				buf.write([]byte(`\\`))
+				continue
+			}
+			if _, err := p.scanner.next(); err != nil {
+				return err
+			}
 
 			switch nextByte {
 			case '"':
@@ -443,54 +1215,19 @@ func (p *parser) parseString(buf *buffer) error {
 				buf.writeByte('\\')
 				buf.writeByte('t')
 			case 'u':
-				// Unicode escape sequence - preserve as-is for now
-				buf.writeByte('\\')
-				buf.writeByte('u')
-				for i := 0; i < 4; i++ {
-					hexByte, err := p.scanner.next()
-					if err != nil {
-						return err
-					}
-					if !isHexDigit(hexByte) {
-						return newEscapeError(p.scanner.position(), "invalid hex digit in unicode escape")
-					}
-					buf.writeByte(hexByte)
+				if err := p.parseUnicodeEscape(buf); err != nil {
+					return err
 				}
 			default:
-				return newEscapeError(p.scanner.position(), "invalid escape sequence")
+				return newEscapeError(escapePos, "invalid escape sequence")
 			}
 		} else {
 			// Regular character
 			if b >= 0x80 {
 				// Multi-byte UTF-8 character - need to read the complete sequence
-				sequence := []byte{b}
-
-				// Determine sequence length based on first byte
-				var seqLen int
-				if b&0xE0 == 0xC0 {
-					seqLen = 2
-				} else if b&0xF0 == 0xE0 {
-					seqLen = 3
-				} else if b&0xF8 == 0xF0 {
-					seqLen = 4
-				} else {
-					return newUnicodeError(p.scanner.position(), "invalid UTF-8 start byte")
+				if err := p.parseUTF8Sequence(b, pos, buf); err != nil {
+					return err
 				}
-
-				// Read remaining bytes of the sequence
-				for i := 1; i < seqLen; i++ {
-					nextByte, err := p.scanner.next()
-					if err != nil {
-						return err
-					}
-					if nextByte&0xC0 != 0x80 {
-						return newUnicodeError(p.scanner.position(), "invalid UTF-8 continuation byte")
-					}
-					sequence = append(sequence, nextByte)
-				}
-
-				// Write the complete UTF-8 sequence
-				buf.write(sequence)
 			} else {
 				// ASCII character - handle control characters
 				if b < 0x20 {
@@ -534,7 +1271,112 @@ func (p *parser) parseString(buf *buffer) error {
 				}
 			}
 		}
+
+		if err := p.checkStringLength(pos); err != nil {
+			return err
+		}
+	}
+}
+
+// checkStringLength reports the byte, escape or UTF-8 sequence at pos that made the
+// current string longer than the WithMaxStringLength limit
+func (p *parser) checkStringLength(pos position) error {
+	if limit := p.options.maxStringLen; limit > 0 && p.scanner.offset-p.stringStart > int64(limit) {
+		return newSyntaxError(pos, "maximum string length exceeded", p.scanner.snippet())
+	}
+	return nil
+}
+
+// escapeLookahead is the number of bytes after the escape character available to
+// the WithEscapeHandler handler
+const escapeLookahead = 16
+
+// handleEscape offers the escape sequence after a consumed backslash to the
+// WithEscapeHandler handler, and writes the decoded bytes if the handler claims it
+func (p *parser) handleEscape(escapePos position, buf *buffer) (bool, error) {
+	data, err := p.scanner.peekBytes(1 + escapeLookahead)
+	if err != nil || len(data) == 0 {
+		return false, err
+	}
+
+	r := bytes.NewReader(data[1:])
+	decoded, err := p.options.escapeHandler(data[0], r)
+	if err != nil {
+		return false, newEscapeError(escapePos, err.Error())
+	}
+	if decoded == nil {
+		return false, nil
+	}
+	if !utf8.Valid(decoded) {
+		if p.options.strictUTF8 {
+			return false, newUnicodeError(escapePos, "escape handler returned invalid UTF-8")
+		}
+		decoded = bytes.ToValidUTF8(decoded, []byte("\uFFFD"))
 	}
+
+	if err := p.scanner.discard(len(data) - r.Len()); err != nil {
+		return false, err
+	}
+	buf.write(encodeEscape(decoded))
+	return true, nil
+}
+
+// parseUTF8Sequence reads the rest of a multi-byte UTF-8 sequence starting with lead
+// Invalid sequences are rejected in strict mode and replaced with U+FFFD otherwise
+func (p *parser) parseUTF8Sequence(lead byte, leadPos position, buf *buffer) error {
+	var seqLen int
+	switch {
+	case lead&0xE0 == 0xC0:
+		seqLen = 2
+	case lead&0xF0 == 0xE0:
+		seqLen = 3
+	case lead&0xF8 == 0xF0:
+		seqLen = 4
+	default:
+		return p.invalidUTF8(buf, 1, leadPos, "invalid UTF-8 start byte")
+	}
+
+	var sequence [4]byte
+	sequence[0] = lead
+	n := 1
+	for n < seqLen {
+		// Peek so that a byte which does not belong to the sequence is kept for the caller
+		nextByte, err := p.scanner.peek()
+		if err == io.EOF && !p.scanner.atBoundary {
+			return newUnicodeError(p.scanner.position(), "incomplete UTF-8 sequence at end of input")
+		}
+		if err != nil {
+			return err
+		}
+		if nextByte&0xC0 != 0x80 {
+			return p.invalidUTF8(buf, n, p.scanner.position(), "invalid UTF-8 continuation byte")
+		}
+		if _, err := p.scanner.next(); err != nil {
+			return err
+		}
+		sequence[n] = nextByte
+		n++
+	}
+
+	// Reject overlong encodings, surrogates and code points beyond U+10FFFF
+	if _, size, err := decodeUTF8Rune(sequence[:n]); err != nil || size != n {
+		return p.invalidUTF8(buf, n, leadPos, "invalid UTF-8 sequence")
+	}
+
+	buf.write(sequence[:n])
+	return nil
+}
+
+// invalidUTF8 handles n invalid bytes according to the strictUTF8 option, reporting pos in strict mode
+// In lenient mode each byte becomes U+FFFD, matching encoding/json
+func (p *parser) invalidUTF8(buf *buffer, n int, pos position, message string) error {
+	if p.options.strictUTF8 {
+		return newUnicodeError(pos, message)
+	}
+	for i := 0; i < n; i++ {
+		buf.write(encodeUTF8Rune(utf8.RuneError))
+	}
+	return nil
 }
 
 // parseBoolean parses true or false
@@ -548,12 +1390,13 @@ func (p *parser) parseBoolean(buf *buffer) error {
 		// Parse "true"
 		expected := "true"
 		for _, char := range expected {
+			pos := p.scanner.position()
 			b, err := p.scanner.next()
 			if err != nil {
 				return err
 			}
 			if b != byte(char) {
-				return newSyntaxError(p.scanner.position(), "invalid boolean value")
+				return newSyntaxError(pos, "invalid boolean value")
 			}
 			buf.writeByte(b)
 		}
@@ -561,12 +1404,13 @@ func (p *parser) parseBoolean(buf *buffer) error {
 		// Parse "false"
 		expected := "false"
 		for _, char := range expected {
+			pos := p.scanner.position()
 			b, err := p.scanner.next()
 			if err != nil {
 				return err
 			}
 			if b != byte(char) {
-				return newSyntaxError(p.scanner.position(), "invalid boolean value")
+				return newSyntaxError(pos, "invalid boolean value")
 			}
 			buf.writeByte(b)
 		}
@@ -581,20 +1425,32 @@ func (p *parser) parseBoolean(buf *buffer) error {
 func (p *parser) parseNull(buf *buffer) error {
 	expected := "null"
 	for _, char := range expected {
+		pos := p.scanner.position()
 		b, err := p.scanner.next()
 		if err != nil {
 			return err
 		}
 		if b != byte(char) {
-			return newSyntaxError(p.scanner.position(), "invalid null value")
+			return newSyntaxError(pos, "invalid null value")
 		}
 		buf.writeByte(b)
 	}
 	return nil
 }
 
-// parseNumber parses a JSON number
-func (p *parser) parseNumber(buf *buffer) error {
+// isLiteralStart checks if a byte may start a literal accepted by parseLenientLiteral
+func isLiteralStart(b byte) bool {
+	switch b {
+	case 't', 'f', 'n', 'T', 'F', 'N':
+		return true
+	}
+	return false
+}
+
+// parseLenientLiteral parses case-insensitive true, false, null, none and nil
+func (p *parser) parseLenientLiteral(buf *buffer) error {
+	start := p.scanner.position()
+	var word []byte
 	for {
 		b, err := p.scanner.peek()
 		if err == io.EOF {
@@ -603,22 +1459,155 @@ func (p *parser) parseNumber(buf *buffer) error {
 		if err != nil {
 			return err
 		}
+		if (b < 'a' || b > 'z') && (b < 'A' || b > 'Z') {
+			break
+		}
+		if _, err := p.scanner.next(); err != nil {
+			return err
+		}
+		word = append(word, b|0x20) // ASCII lowercase
+	}
 
-		// Check if character is part of a number
-		if (b >= '0' && b <= '9') || b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E' {
-			b, err := p.scanner.next()
-			if err != nil {
+	switch string(word) {
+	case "true", "false", "null":
+		buf.write(word)
+	case "none", "nil":
+		buf.write([]byte("null"))
+	default:
+		return newSyntaxError(start, "invalid literal value")
+	}
+	return nil
+}
+
+// parseNumber parses a number following the RFC 8259 grammar: an optional minus sign,
+// an integer part, an optional fraction and an optional exponent
+func (p *parser) parseNumber(buf *buffer) error {
+	b, err := p.peekNumber()
+	if err != nil {
+		return err
+	}
+	if b == '-' {
+		if err := p.acceptNumber(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+	}
+
+	// The integer part is a single zero or starts with a nonzero digit
+	if b == '0' {
+		if err := p.acceptNumber(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+		if b >= '0' && b <= '9' {
+			return newSyntaxError(p.scanner.position(), "invalid number: leading zero", p.scanner.snippet())
+		}
+	} else {
+		if err := p.parseDigits(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+	}
+
+	// Fraction
+	if b == '.' {
+		if err := p.acceptNumber(buf); err != nil {
+			return err
+		}
+		if err := p.parseDigits(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+	}
+
+	// Exponent
+	if b == 'e' || b == 'E' {
+		if err := p.acceptNumber(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+		if b == '+' || b == '-' {
+			if err := p.acceptNumber(buf); err != nil {
 				return err
 			}
-			buf.writeByte(b)
-		} else {
-			// End of number
-			break
 		}
+		if err := p.parseDigits(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+	}
+
+	// No valid JSON continues a complete number with one of these, as in 1.2.3 or 1e5e5
+	if b == '.' || b == 'e' || b == 'E' || b == '+' || b == '-' {
+		return newSyntaxError(p.scanner.position(), "invalid number: unexpected "+quoteByte(b), p.scanner.snippet())
 	}
 	return nil
 }
 
+// parseDigits parses the run of digits that must follow in a number
+func (p *parser) parseDigits(buf *buffer) error {
+	b, err := p.scanner.peek()
+	if err != nil {
+		return err
+	}
+	if b < '0' || b > '9' {
+		return newSyntaxError(p.scanner.position(), "invalid number: expected digit, found "+quoteByte(b), p.scanner.snippet())
+	}
+	for b >= '0' && b <= '9' {
+		if err := p.acceptNumber(buf); err != nil {
+			return err
+		}
+		if b, err = p.peekNumber(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// peekNumber returns the next byte of a number, or 0 at the end of the input, where
+// a number may end
+func (p *parser) peekNumber() (byte, error) {
+	b, err := p.scanner.peek()
+	if err == io.EOF {
+		return 0, nil
+	}
+	return b, err
+}
+
+// acceptNumber consumes the peeked byte of a number into buf
+func (p *parser) acceptNumber(buf *buffer) error {
+	b, err := p.scanner.next()
+	if err != nil {
+		return err
+	}
+	buf.writeByte(b)
+	return nil
+}
+
+// checkCount rejects the count-th entry of an object or array beyond limit, 0 meaning
+// no limit. The error points at the start of the entry
+func (p *parser) checkCount(count, limit int, message string) error {
+	if limit == 0 || count <= limit {
+		return nil
+	}
+	if err := p.scanner.skipWhitespace(); err != nil {
+		return err
+	}
+	return newSyntaxError(p.scanner.position(), message, p.scanner.snippet())
+}
+
 // checkDepth validates nesting depth against limits
 func (p *parser) checkDepth() error {
 	if p.depth >= p.options.maxDepth {
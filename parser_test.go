@@ -1,7 +1,15 @@
 package jsonex
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestParser_MalformedJSON(t *testing.T) {
@@ -108,6 +116,103 @@ func TestParser_MaxDepthEnforcement(t *testing.T) {
 	}
 }
 
+func TestParser_MaxStringLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		offset int64 // expected error offset, -1 if no error
+	}{
+		{"Value within limit", `{"key": "abcd"}`, -1},
+		{"Value too long", `{"key": "abcde"}`, 13},
+		{"Key too long", `{"abcde": 1}`, 6},
+		{"Escape crossing limit", `["abc\n"]`, 5},
+		{"UTF-8 sequence crossing limit", `["abcé"]`, 5},
+		{"Escapes within limit", `["\n\t"]`, -1},
+		{"Long string in garbage", `log "abcdefgh" {"a": "b"}`, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result interface{}
+			err := Unmarshal([]byte(test.input), &result, WithMaxStringLength(4))
+			if test.offset < 0 {
+				if err != nil {
+					t.Errorf("Unmarshal(%s) failed: %v", test.input, err)
+				}
+				return
+			}
+
+			var jsonErr *Error
+			if !errors.As(err, &jsonErr) || jsonErr.Type != ErrSyntax || jsonErr.Message != "maximum string length exceeded" {
+				t.Fatalf("Unmarshal(%s) = %v, expected maximum string length error", test.input, err)
+			}
+			if jsonErr.Position.Offset != test.offset {
+				t.Errorf("Unmarshal(%s) error offset = %d, expected %d", test.input, jsonErr.Position.Offset, test.offset)
+			}
+		})
+	}
+
+	// The Decoder and the strict validators enforce the limit as well
+	decoder := New(strings.NewReader(`{"key": "abcde"}`), WithMaxStringLength(4))
+	var result interface{}
+	if err := decoder.Decode(&result); err == nil {
+		t.Errorf("Decode = %v, expected error", result)
+	}
+	if err := ValidateFragment([]byte(`["abcde"]`), WithMaxStringLength(4)); err == nil {
+		t.Error("ValidateFragment succeeded, expected error")
+	}
+}
+
+func TestParser_MaxEntries(t *testing.T) {
+	array := func(n int) string {
+		return "[" + strings.Repeat("0,", n-1) + "0]"
+	}
+
+	var result interface{}
+	if err := Unmarshal([]byte(array(1000)), &result, WithMaxArrayElements(1000)); err != nil {
+		t.Errorf("Unmarshal of 1000 elements failed: %v", err)
+	}
+
+	err := Unmarshal([]byte(array(1001)), &result, WithMaxArrayElements(1000))
+	var jsonErr *Error
+	if !errors.As(err, &jsonErr) || jsonErr.Type != ErrSyntax || jsonErr.Message != "maximum array elements exceeded" {
+		t.Fatalf("Unmarshal of 1001 elements = %v, expected maximum array elements error", err)
+	}
+	if jsonErr.Position.Offset != 2001 {
+		t.Errorf("error offset = %d, expected 2001", jsonErr.Position.Offset)
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		offset int64 // expected error offset, -1 if no error
+	}{
+		{"Keys within limit", `{"a": 1, "b": 2}`, -1},
+		{"Too many keys", `{"a": 1, "b": 2, "c": 3}`, 17},
+		{"Repeated keys count", `{"a": 1, "a": 2, "a": 3}`, 17},
+		{"Nested object", `{"a": {"b": 1, "c": 2, "d": 3}}`, 23},
+		{"Objects in array are separate", `[{"a": 1, "b": 2}, {"c": 3, "d": 4}]`, -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Unmarshal([]byte(test.input), &result, WithMaxObjectKeys(2))
+			if test.offset < 0 {
+				if err != nil {
+					t.Errorf("Unmarshal(%s) failed: %v", test.input, err)
+				}
+				return
+			}
+			var jsonErr *Error
+			if !errors.As(err, &jsonErr) || jsonErr.Message != "maximum object keys exceeded" {
+				t.Fatalf("Unmarshal(%s) = %v, expected maximum object keys error", test.input, err)
+			}
+			if jsonErr.Position.Offset != test.offset {
+				t.Errorf("Unmarshal(%s) error offset = %d, expected %d", test.input, jsonErr.Position.Offset, test.offset)
+			}
+		})
+	}
+}
+
 func TestParser_NumberFormats(t *testing.T) {
 	tests := []struct {
 		name string
@@ -150,6 +255,70 @@ func TestParser_NumberFormats(t *testing.T) {
 	}
 }
 
+func TestParser_InvalidNumbers(t *testing.T) {
+	tests := []struct {
+		input   string
+		offset  int64
+		message string
+	}{
+		{`[1.2.3]`, 4, "invalid number: unexpected '.'"},
+		{`[--5]`, 2, "invalid number: expected digit, found '-'"},
+		{`[1e]`, 3, "invalid number: expected digit, found ']'"},
+		{`[1.]`, 3, "invalid number: expected digit, found ']'"},
+		{`[1e+]`, 4, "invalid number: expected digit, found ']'"},
+		{`[-]`, 2, "invalid number: expected digit, found ']'"},
+		{`[-x]`, 2, "invalid number: expected digit, found 'x'"},
+		{`[1E5e5]`, 4, "invalid number: unexpected 'e'"},
+		{`[1+2]`, 2, "invalid number: unexpected '+'"},
+		{`[+.]`, 1, "unexpected character '+'"},
+		{`[.5]`, 1, "unexpected character '.'"},
+		{`[01]`, 2, "invalid number: leading zero"},
+		{`[-01]`, 3, "invalid number: leading zero"},
+		{`[00.5]`, 2, "invalid number: leading zero"},
+	}
+
+	for _, test := range tests {
+		for name, validate := range map[string]func([]byte) error{
+			"ValidateFragment": func(data []byte) error { return ValidateFragment(data) },
+			"Decode": func(data []byte) error {
+				var v interface{}
+				return New(bytes.NewReader(data)).Decode(&v)
+			},
+		} {
+			err := validate([]byte(test.input))
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != ErrSyntax {
+				t.Errorf("%s(%s) = %v, expected syntax error", name, test.input, err)
+				continue
+			}
+			if jsonErr.Position.Offset != test.offset || jsonErr.Message != test.message {
+				t.Errorf("%s(%s) = %v, expected %q at offset %d", name, test.input, err, test.message, test.offset)
+			}
+		}
+	}
+
+	valid := `[0, -0, 0.5, 0e1, -0.0e+0, 1E-7, 10, 123, 1.5e10, -2.5E-5]`
+	if err := ValidateFragment([]byte(valid)); err != nil {
+		t.Errorf("ValidateFragment(%s) failed: %v", valid, err)
+	}
+
+	// Leading zeros are rejected on the fast path, the robust path and after garbage alike
+	for _, opts := range [][]Option{nil, {WithMaxDepth(100)}} {
+		for _, input := range []string{`{"a": 01}`, `log {"a": -01}`} {
+			var v interface{}
+			if err := Unmarshal([]byte(input), &v, opts...); err == nil {
+				t.Errorf("Unmarshal(%s) = %v, expected error", input, v)
+			}
+		}
+	}
+
+	// A number may end the input
+	var n float64
+	if err := Unmarshal([]byte("value: 12.5e1"), &n, WithAllowScalars(true)); err != nil || n != 125 {
+		t.Errorf("Unmarshal of a number at the end = %v, %v, expected 125", n, err)
+	}
+}
+
 func TestParser_NestedStructures(t *testing.T) {
 	// Complex nested structure
 	data := []byte(`{
@@ -184,4 +353,763 @@ func TestParser_NestedStructures(t *testing.T) {
 	if len(array) != 2 {
 		t.Errorf("Expected array length 2, got %d", len(array))
 	}
-}
\ No newline at end of file
+}
+
+func TestParser_StrictUTF8(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		offset int64
+	}{
+		{"Invalid byte in key", []byte("{\"k\xffey\": \"value\"}"), 3},
+		{"Invalid byte in value", []byte("{\"key\": \"va\xfflue\"}"), 11},
+		{"Overlong encoding in value", []byte("{\"key\": \"\xc0\x80\"}"), 9},
+		{"Overlong three-byte encoding", []byte("{\"key\": \"ab\xe0\x80\xaf\"}"), 11},
+		{"Encoded surrogate", []byte("{\"key\": \"\xed\xa0\x80\"}"), 9},
+		{"Beyond U+10FFFF", []byte("{\"key\": \"\xf4\x90\x80\x80\"}"), 9},
+		{"Truncated sequence in value", []byte("{\"key\": \"\xe4\xb8\"}"), 11},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := New(bytes.NewReader(test.data))
+			var result map[string]interface{}
+			err := decoder.Decode(&result)
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != ErrUnicode {
+				t.Fatalf("Expected unicode error, got %v", err)
+			}
+			if jsonErr.Position.Offset != test.offset {
+				t.Errorf("Expected error at offset %d, got %v", test.offset, jsonErr.Position)
+			}
+
+			if err := Unmarshal(test.data, &result); err == nil {
+				t.Error("Expected Unmarshal to reject invalid UTF-8")
+			}
+		})
+	}
+}
+
+func TestParser_LenientUTF8(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected map[string]interface{}
+	}{
+		{
+			name:     "Invalid byte in key",
+			data:     []byte("{\"k\xffey\": \"value\"}"),
+			expected: map[string]interface{}{"k�ey": "value"},
+		},
+		{
+			name:     "Invalid byte in value",
+			data:     []byte("{\"key\": \"va\xfflue\"}"),
+			expected: map[string]interface{}{"key": "va�lue"},
+		},
+		{
+			name:     "Overlong encoding in value",
+			data:     []byte("{\"key\": \"\xc0\x80\"}"),
+			expected: map[string]interface{}{"key": "��"},
+		},
+		{
+			name:     "Truncated sequence before closing quote",
+			data:     []byte("{\"key\": \"\xe4\xb8\"}"),
+			expected: map[string]interface{}{"key": "��"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoder := New(bytes.NewReader(test.data), WithStrictUTF8(false))
+			var decoded map[string]interface{}
+			if err := decoder.Decode(&decoded); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, test.expected) {
+				t.Errorf("Decode = %q, expected %q", decoded, test.expected)
+			}
+
+			var unmarshaled map[string]interface{}
+			if err := Unmarshal(test.data, &unmarshaled, WithStrictUTF8(false)); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(unmarshaled, test.expected) {
+				t.Errorf("Unmarshal = %q, expected %q", unmarshaled, test.expected)
+			}
+		})
+	}
+}
+
+func TestParser_InvalidUTF8InGarbage(t *testing.T) {
+	// Garbage is never part of the extracted value, so it is skipped in both modes
+	data := []byte("\xff\xfe garbage \xc0\x80 {\"key\": \"value\"} \xe4 trailing")
+
+	for _, strict := range []bool{true, false} {
+		var result map[string]interface{}
+		if err := Unmarshal(data, &result, WithStrictUTF8(strict)); err != nil {
+			t.Fatalf("Unmarshal (strict=%v) failed: %v", strict, err)
+		}
+		if result["key"] != "value" {
+			t.Errorf("Unmarshal (strict=%v) = %v", strict, result)
+		}
+
+		result = nil
+		if err := New(bytes.NewReader(data), WithStrictUTF8(strict)).Decode(&result); err != nil {
+			t.Fatalf("Decode (strict=%v) failed: %v", strict, err)
+		}
+		if result["key"] != "value" {
+			t.Errorf("Decode (strict=%v) = %v", strict, result)
+		}
+	}
+}
+
+func TestParser_LongestErrorPosition(t *testing.T) {
+	prefix := strings.Repeat("x", 990) + "\n\nabcdefgh"
+
+	t.Run("Depth error", func(t *testing.T) {
+		data := []byte(prefix + `{"a": {"b": {"c": 1}}}`)
+
+		var result interface{}
+		err := Unmarshal(data, &result, WithMaxDepth(2))
+		jsonErr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("Expected *Error, got %v", err)
+		}
+		if jsonErr.Position.Offset < 1000 {
+			t.Errorf("Expected absolute offset beyond 1000, got %v", jsonErr.Position)
+		}
+		if jsonErr.Position.Line != 3 {
+			t.Errorf("Expected line 3, got %v", jsonErr.Position)
+		}
+	})
+
+	t.Run("Unicode error", func(t *testing.T) {
+		data := []byte(prefix + "{\"a\": \"\xff\"}")
+
+		var result interface{}
+		err := Unmarshal(data, &result)
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrUnicode {
+			t.Fatalf("Expected unicode error, got %v", err)
+		}
+		if jsonErr.Position.Offset != 1007 {
+			t.Errorf("Expected offset 1007, got %v", jsonErr.Position)
+		}
+	})
+
+	t.Run("Missing colon", func(t *testing.T) {
+		err := ValidateFragment([]byte(`{"k" 1}`))
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrSyntax {
+			t.Fatalf("Expected syntax error, got %v", err)
+		}
+		if want := (Position{Offset: 5, Line: 1, Column: 6}); jsonErr.Position != want {
+			t.Errorf("Expected %v, got %v", want, jsonErr.Position)
+		}
+
+		// Garbage before the value counts toward the position in the original input
+		decoder := New(strings.NewReader("xx\n  {\"k\" 1}"))
+		var result interface{}
+		err = decoder.Decode(&result)
+		jsonErr, ok = err.(*Error)
+		if !ok || jsonErr.Type != ErrSyntax {
+			t.Fatalf("Expected syntax error, got %v", err)
+		}
+		if want := (Position{Offset: 10, Line: 2, Column: 8}); jsonErr.Position != want {
+			t.Errorf("Expected %v, got %v", want, jsonErr.Position)
+		}
+	})
+
+	t.Run("Multi-byte characters", func(t *testing.T) {
+		// Columns count characters while offsets count bytes
+		input := "エラー\nログ: {\"名前\" 1}"
+		want := Position{Offset: 28, Line: 2, Column: 11}
+
+		var result interface{}
+		err := New(strings.NewReader(input)).Decode(&result)
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Position != want {
+			t.Errorf("Decode = %v, expected error at %v", err, want)
+		}
+
+		// The robust path reports limit errors, here at the first character of the key
+		want = Position{Offset: 20, Line: 2, Column: 7}
+		err = Unmarshal([]byte(input), &result, WithMaxStringLength(1))
+		jsonErr, ok = err.(*Error)
+		if !ok || jsonErr.Position != want {
+			t.Errorf("Unmarshal = %v, expected error at %v", err, want)
+		}
+	})
+}
+
+func TestParser_LenientLiterals(t *testing.T) {
+	data := []byte(`log: {"a": TRUE, "b": None, "c": False, "d": nil, "e": NULL, "f": [True, null]}`)
+	expected := map[string]interface{}{
+		"a": true,
+		"b": nil,
+		"c": false,
+		"d": nil,
+		"e": nil,
+		"f": []interface{}{true, nil},
+	}
+
+	var unmarshaled map[string]interface{}
+	if err := Unmarshal(data, &unmarshaled, WithLenientLiterals(true)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(unmarshaled, expected) {
+		t.Errorf("Unmarshal = %v, expected %v", unmarshaled, expected)
+	}
+
+	var decoded map[string]interface{}
+	if err := New(bytes.NewReader(data), WithLenientLiterals(true)).Decode(&decoded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("Decode = %v, expected %v", decoded, expected)
+	}
+
+	// Unknown words are still rejected
+	var result interface{}
+	if err := Unmarshal([]byte(`{"a": yes}`), &result, WithLenientLiterals(true)); err == nil {
+		t.Error("Expected error for unknown literal")
+	}
+
+	// Strict lowercase literals remain the default
+	if err := Unmarshal([]byte(`{"a": TRUE, "b": None}`), &result); err == nil {
+		t.Error("Expected error for non-lowercase literals by default")
+	}
+}
+
+func TestParser_AllocBudget(t *testing.T) {
+	errQuota := errors.New("quota exceeded")
+	data := []byte(`{"data": "` + strings.Repeat("x", 1<<16) + `"}`)
+
+	charged := 0
+	budget := func(n int) error {
+		charged += n
+		if charged > 16*1024 {
+			return errQuota
+		}
+		return nil
+	}
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithAllocBudget(budget)); !errors.Is(err, errQuota) {
+		t.Errorf("Unmarshal = %v, expected quota error", err)
+	}
+
+	charged = 0
+	if err := New(bytes.NewReader(data), WithAllocBudget(budget)).Decode(&result); !errors.Is(err, errQuota) {
+		t.Errorf("Decode = %v, expected quota error", err)
+	}
+
+	// A generous budget lets the parse complete while still being charged
+	charged = 0
+	generous := func(n int) error {
+		charged += n
+		return nil
+	}
+	if err := Unmarshal(data, &result, WithAllocBudget(generous)); err != nil {
+		t.Fatalf("Unmarshal with generous budget failed: %v", err)
+	}
+	if charged < 1<<16 {
+		t.Errorf("Charged %d bytes, expected at least %d", charged, 1<<16)
+	}
+}
+
+func TestParser_TruncatedUTF8AtEOF(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("{\"s\":\"abc\xe4"),
+		[]byte("{\"s\":\"abc\xe4\xb8"),
+		[]byte("log: [\"\xf0\x9f\x98"),
+		[]byte("{\"k\xc3"),
+	}
+
+	for _, data := range inputs {
+		for _, strict := range []bool{true, false} {
+			decoder := New(bytes.NewReader(data), WithStrictUTF8(strict))
+			var result interface{}
+			err := decoder.Decode(&result)
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != ErrUnicode || jsonErr.Message != "incomplete UTF-8 sequence at end of input" {
+				t.Errorf("Decode(%q) strict=%v = %v, expected incomplete UTF-8 error", data, strict, err)
+				continue
+			}
+			if jsonErr.Position.Offset != int64(len(data)) {
+				t.Errorf("Decode(%q) error offset = %d, expected %d", data, jsonErr.Position.Offset, len(data))
+			}
+
+			err = Unmarshal(data, &result, WithStrictUTF8(strict))
+			if !isUnicodeError(err) {
+				t.Errorf("Unmarshal(%q) strict=%v = %v, expected unicode error", data, strict, err)
+			}
+		}
+	}
+}
+
+func TestParser_WindowsPathStrings(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"Users directory", `{"path": "C:\Users\x"}`, `C:\Users\x`},
+		{"Lowercase u is not a unicode escape", `log: {"path": "C:\users\me"}`, `C:\users\me`},
+		{"Partial hex digits", `{"path": "D:\u12zz"}`, `D:\u12zz`},
+		{"Program files", `{"path": "C:\Program Files (x86)\App\app.exe"}`, `C:\Program Files (x86)\App\app.exe`},
+		{"UNC path", `{"path": "\\server\share\dir"}`, `\server\share\dir`},
+		{"Trailing backslash before quote", `{"path": "C:\Temp\\"}`, `C:\Temp\`},
+		{"Valid escapes keep their meaning", `{"path": "C:\new\u0041"}`, "C:\newA"},
+		{"Non-ASCII after backslash", `{"path": "C:\日本"}`, `C:\日本`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result map[string]interface{}
+			if err := Unmarshal([]byte(test.data), &result, WithWindowsPathStrings(true)); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if result["path"] != test.expected {
+				t.Errorf("Unmarshal = %q, expected %q", result["path"], test.expected)
+			}
+
+			var decoded map[string]interface{}
+			if err := New(strings.NewReader(test.data), WithWindowsPathStrings(true)).Decode(&decoded); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if decoded["path"] != test.expected {
+				t.Errorf("Decode = %q, expected %q", decoded["path"], test.expected)
+			}
+		})
+	}
+
+	// Strict by default
+	var result map[string]interface{}
+	err := New(strings.NewReader(`{"path": "C:\Users\x"}`)).Decode(&result)
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Type != ErrEscape {
+		t.Errorf("Decode without option = %v, expected escape error", err)
+	}
+}
+
+func TestParser_EscapeHandler(t *testing.T) {
+	// hexEscapes decodes \xHH and leaves other escapes to the parser
+	hexEscapes := func(b byte, r *bytes.Reader) ([]byte, error) {
+		if b != 'x' {
+			return nil, nil
+		}
+		var digits [2]byte
+		if _, err := io.ReadFull(r, digits[:]); err != nil || !isHexDigit(digits[0]) || !isHexDigit(digits[1]) {
+			return nil, errors.New("invalid hex escape")
+		}
+		var value [1]byte
+		if _, err := hex.Decode(value[:], digits[:]); err != nil {
+			return nil, err
+		}
+		return value[:], nil
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"Hex escapes", `{"s": "\x41\x42c"}`, "ABc"},
+		{"Standard escapes", `{"s": "\x41\n\u00e9\""}`, "A\né\""},
+		{"Decoded quote and control byte", `log: {"s": "\x22\x01"} tail`, "\"\x01"},
+		{"Escape at end of string", `{"s": "a\x7a"}`, "az"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result map[string]string
+			if err := Unmarshal([]byte(test.data), &result, WithEscapeHandler(hexEscapes)); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if result["s"] != test.expected {
+				t.Errorf("Unmarshal = %q, expected %q", result["s"], test.expected)
+			}
+
+			result = nil
+			if err := New(strings.NewReader(test.data), WithEscapeHandler(hexEscapes)).Decode(&result); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if result["s"] != test.expected {
+				t.Errorf("Decode = %q, expected %q", result["s"], test.expected)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name    string
+		data    string
+		errType ErrorType
+		offset  int64
+	}{
+		{"Handler error", `["\xZZ"]`, ErrEscape, 3},
+		{"Unclaimed invalid escape", `["a\q"]`, ErrEscape, 4},
+		{"Invalid UTF-8 result", `["\xff"]`, ErrUnicode, 3},
+	}
+	for _, test := range errorTests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateFragment([]byte(test.data), WithEscapeHandler(hexEscapes))
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != test.errType || jsonErr.Position.Offset != test.offset {
+				t.Errorf("ValidateFragment(%s) = %v, expected %v at offset %d", test.data, err, test.errType, test.offset)
+			}
+		})
+	}
+
+	// A handler may claim standard escapes as well
+	claimNewline := func(b byte, r *bytes.Reader) ([]byte, error) {
+		if b == 'n' {
+			return []byte("\\N"), nil
+		}
+		return nil, nil
+	}
+	var result []string
+	if err := Unmarshal([]byte(`["a\nb\tc"]`), &result, WithEscapeHandler(claimNewline)); err != nil || result[0] != "a\\Nb\tc" {
+		t.Errorf("Unmarshal = %q, %v, expected %q", result, err, "a\\Nb\tc")
+	}
+}
+
+func TestParser_LongestRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+	}{
+		{"Truncated outer value", `{"a": {"b": [1, 2]}, "c": `, nil, `{"b": [1, 2]}`},
+		{"Value at failing byte", `{"a": 1 {"b": 2}}`, nil, `{"b": 2}`},
+		{"Value in string of broken value", `{"msg": "got [1, 2, 3]" oops`, nil, `[1, 2, 3]`},
+		{"Value in unterminated string", `{"code": "f() { return [1, 2]; }`, nil, `[1, 2]`},
+		{"Nested values are skipped", `[[1], [2, 3]] [4]`, nil, `[[1], [2, 3]]`},
+		{"Key of broken object", `{"a long key" 1}`, []Option{WithAllowScalars(true)}, `"a long key"`},
+		{"Quote closing a string", `"a" and "b"`, []Option{WithAllowScalars(true)}, `" and "`},
+		{"Nested value and unterminated string", `{"a": [1, 2], "b": "x [3, 4, 5, 6]`, nil, `[3, 4, 5, 6]`},
+		{"Value across string of broken value", `{"log": "{"inner": 1}"}`, nil, `{"inner": 1}`},
+		{"Value with strings in string of broken value", `msg={"log": "payload {"user": "bob", "id": 7} end"}`, nil, `{"user": "bob", "id": 7}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result, expected interface{}
+			if err := json.Unmarshal([]byte(test.expected), &expected); err != nil {
+				t.Fatalf("json.Unmarshal(%s) failed: %v", test.expected, err)
+			}
+			if err := Unmarshal([]byte(test.input), &result, test.opts...); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Unmarshal(%s) = %v, expected %v", test.input, result, expected)
+			}
+		})
+	}
+}
+
+func TestParser_ResetFor(t *testing.T) {
+	data := []byte("[1, oops\n{\"a\": [true]}")
+	p := newBytesParser(data, position{line: 1, column: 1}, defaultOptions())
+	defer p.scanner.release()
+
+	if _, _, err := p.tryParseFromPosition(data, position{line: 1, column: 1}); err == nil {
+		t.Fatal("tryParseFromPosition of a broken array succeeded")
+	}
+
+	// After a failed candidate, the parser parses the next one from a clean state
+	base := position{line: 1, column: 1}.advanceAll(data[:9])
+	result, consumed, err := p.tryParseFromPosition(data[9:], base)
+	if err != nil || string(result) != `{"a":[true]}` || consumed != 13 {
+		t.Errorf("tryParseFromPosition = %s, %d, %v", result, consumed, err)
+	}
+	if pos := p.scanner.position(); pos.offset != 22 || pos.line != 2 || pos.column != 14 {
+		t.Errorf("position after the value = %+v, expected offset 22 on line 2, column 14", pos)
+	}
+}
+
+func TestParser_LongestPathologicalInput(t *testing.T) {
+	// Each candidate start used to be parsed again to the end, taking hours on these inputs
+	inputs := map[string]string{
+		"Open brackets":    strings.Repeat("[", 1<<20),
+		"Open objects":     strings.Repeat(`{"a":`, 1<<18),
+		"Open strings":     strings.Repeat(`["",`, 1<<18),
+		"Strings in array": `["` + strings.Repeat(`[1,"x",`, 1<<17),
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			var result interface{}
+			if err := Unmarshal([]byte(input), &result); err == nil {
+				t.Errorf("Unmarshal = %v, expected error", result)
+			}
+		})
+	}
+}
+
+func TestParser_EachRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected []string
+	}{
+		{"Nested values of broken value", `[{"a": 1}, [2], {"b": {"c": 3}} oops [4]`, nil, []string{`{"a":1}`, `[2]`, `{"b":{"c":3}}`, `[4]`}},
+		{"Value at failing byte", `{"a": 1 {"b": 2}}`, nil, []string{`{"b":2}`}},
+		{"Values in strings of broken value", `{"x": "[1]", "y": {"z": "[2]"} oops`, nil, []string{`[1]`, `{"z":"[2]"}`}},
+		{"Value in unterminated string", `{"code": "f() { return [1, 2]; }`, nil, []string{`[1,2]`}},
+		{"Key of broken object", `{"k" 1}`, []Option{WithAllowScalars(true)}, []string{`"k"`, `1`}},
+		{"Value across string of broken value", `{"log": "{"inner": 1}"}`, nil, []string{`{"inner":1}`}},
+		{"Value after quote in string of broken value", `"," {"[,1[  ""]]`, nil, []string{`[""]`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := UnmarshalAll([]byte(test.input), test.opts...)
+			if err != nil {
+				t.Fatalf("UnmarshalAll failed: %v", err)
+			}
+			var got []string
+			for _, value := range values {
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, value); err != nil {
+					t.Fatalf("value %s is not valid JSON: %v", value, err)
+				}
+				got = append(got, compact.String())
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("UnmarshalAll(%s) = %q, expected %q", test.input, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestParser_EachMatchesDecoder(t *testing.T) {
+	// UnmarshalAll skips the failed candidates that Decoder retries byte by byte, so both
+	// must return the same values
+	inputs := []string{
+		`"," {"[,1[  ""]]`,
+		`{"log": "{"inner": 1}"}`,
+		`msg={"log": "payload {"user": "bob", "id": 7} end"}`,
+		`[{"a": 1}, [2], {"b": {"c": 3}} oops [4]`,
+		`{"x": "[1]", "y": {"z": "[2]"} oops`,
+		`{"a": 1 {"b": 2}} [3] {"c": "[4`,
+		`[[[ {"a": "]"} ] "x" [1]`,
+	}
+
+	for _, input := range inputs {
+		var expected []interface{}
+		decoder := New(strings.NewReader(input))
+		// Decode reports a failed candidate and goes on after it
+		for range len(input) + 1 {
+			var value interface{}
+			err := decoder.Decode(&value)
+			if err == io.EOF {
+				break
+			}
+			if err == nil {
+				expected = append(expected, value)
+			}
+		}
+
+		values, _ := UnmarshalAll([]byte(input))
+		var got []interface{}
+		for _, raw := range values {
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				t.Fatalf("value %s is not valid JSON: %v", raw, err)
+			}
+			got = append(got, value)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("UnmarshalAll(%s) = %v, Decode returned %v", input, got, expected)
+		}
+	}
+}
+
+func TestParser_EachPathologicalInput(t *testing.T) {
+	// Each candidate start used to be parsed again to the end, as in parseLongest
+	inputs := map[string]string{
+		"Open brackets":     strings.Repeat("[", 1<<20),
+		"Open objects":      strings.Repeat(`{"a":`, 1<<18),
+		"Open strings":      strings.Repeat(`["",`, 1<<18),
+		"Strings in array":  `["` + strings.Repeat(`[1,"x",`, 1<<17),
+		"Arrays in strings": strings.Repeat(`,["`, 1<<18),
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			if values, err := UnmarshalAll([]byte(input)); err == nil {
+				t.Errorf("UnmarshalAll = %q, expected error", values)
+			}
+		})
+	}
+}
+
+func TestParser_AllowComments(t *testing.T) {
+	input := `config:
+{
+	// comment before a key
+	"name": "a // not a comment /* either */", /* after a value */
+	"list": [1, /* between elements */ 2 // trailing
+		, 3],
+	/* before a key */ "nested": {"k" /* before colon */ : /* before value */ true}
+}`
+	expected := map[string]interface{}{
+		"name":   "a // not a comment /* either */",
+		"list":   []interface{}{float64(1), float64(2), float64(3)},
+		"nested": map[string]interface{}{"k": true},
+	}
+
+	var result map[string]interface{}
+	if err := Unmarshal([]byte(input), &result, WithAllowComments()); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Unmarshal = %v, expected %v", result, expected)
+	}
+
+	result = nil
+	if err := New(strings.NewReader(input), WithAllowComments(), WithBufferSize(8)).Decode(&result); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Decode = %v, expected %v", result, expected)
+	}
+
+	// Comments stay invalid by default
+	if err := Unmarshal([]byte(input), &result); err == nil {
+		t.Errorf("Unmarshal without WithAllowComments = %v, expected error", result)
+	}
+
+	invalid := []string{
+		`{"a": 1 /* unterminated }`,
+		`{"a": / 1}`,
+		`[1, /x 2]`,
+	}
+	for _, input := range invalid {
+		if err := ValidateFragment([]byte(input), WithAllowComments()); err == nil {
+			t.Errorf("ValidateFragment(%s) succeeded, expected error", input)
+		}
+	}
+}
+
+func TestParser_AllowTrailingCommas(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"a": 1,}`, map[string]interface{}{"a": float64(1)}},
+		{`[1, 2, ]`, []interface{}{float64(1), float64(2)}},
+		{"text {\"a\": [1,\n], \"b\": {\"c\": true,\n},\n} text", map[string]interface{}{
+			"a": []interface{}{float64(1)},
+			"b": map[string]interface{}{"c": true},
+		}},
+	}
+
+	for _, test := range tests {
+		var result interface{}
+		if err := Unmarshal([]byte(test.input), &result, WithAllowTrailingCommas()); err != nil {
+			t.Errorf("Unmarshal(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Unmarshal(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+
+		// The extracted JSON is valid for encoding/json
+		raw, err := Query([]byte(test.input), "", WithAllowTrailingCommas())
+		if err != nil || !json.Valid(raw) {
+			t.Errorf("Query(%q) = %s, %v, expected valid JSON", test.input, raw, err)
+		}
+	}
+
+	invalid := []string{`[1,,]`, `[,]`, `{,}`, `{"a": 1,,}`}
+	for _, input := range invalid {
+		if err := ValidateFragment([]byte(input), WithAllowTrailingCommas()); err == nil {
+			t.Errorf("ValidateFragment(%s) succeeded, expected error", input)
+		}
+	}
+
+	// Trailing commas stay invalid by default
+	if err := ValidateFragment([]byte(`{"a": 1,}`)); err == nil {
+		t.Error("ValidateFragment without WithAllowTrailingCommas succeeded, expected error")
+	}
+}
+
+func TestParser_DisallowDuplicateKeys(t *testing.T) {
+	valid := []string{
+		`{"a":{"a":1}}`,
+		`{"a":1,"b":{"a":2,"b":3},"c":[{"a":1},{"a":2}]}`,
+		`{"a":1,"A":2}`,
+	}
+	for _, input := range valid {
+		var result interface{}
+		if err := Unmarshal([]byte(input), &result, WithDisallowDuplicateKeys()); err != nil {
+			t.Errorf("Unmarshal(%s) failed: %v", input, err)
+		}
+	}
+
+	invalid := []struct {
+		input  string
+		offset int64
+	}{
+		{`{"a":1,"a":2}`, 7},
+		{`{"x":{"b":1,"c":2,"b":3}}`, 18},
+		{`{"k":1,"k":2}`, 7},
+	}
+	for _, test := range invalid {
+		err := ValidateFragment([]byte(test.input), WithDisallowDuplicateKeys())
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrSyntax || jsonErr.Position.Offset != test.offset || !strings.Contains(jsonErr.Message, "duplicate key") {
+			t.Errorf("ValidateFragment(%s) = %v, expected duplicate key error at offset %d", test.input, err, test.offset)
+		}
+
+		// The fast path must not accept the duplicate either
+		var result interface{}
+		if err := Unmarshal([]byte(test.input), &result, WithDisallowDuplicateKeys()); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, expected error", test.input, result)
+		}
+		if err := Unmarshal([]byte(test.input), &result); err != nil {
+			t.Errorf("Unmarshal(%s) without the option failed: %v", test.input, err)
+		}
+	}
+}
+
+func TestParser_ExtraWhitespace(t *testing.T) {
+	inputs := []string{
+		"{\v\"a\"\f:\v1,\f\"b\": [true\v, null]}",
+		"{\u00a0\"a\":\u00a01, \"b\":\u00a0[true,\u3000null]\u00a0}",
+	}
+	opts := []Option{WithExtraWhitespace('\v', '\f', '\u00a0', '\u3000')}
+	expected := map[string]interface{}{"a": float64(1), "b": []interface{}{true, nil}}
+
+	for _, input := range inputs {
+		var result map[string]interface{}
+		if err := Unmarshal([]byte(input), &result, opts...); err != nil {
+			t.Fatalf("Unmarshal(%q) failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("Unmarshal(%q) = %v, expected %v", input, result, expected)
+		}
+
+		// Multi-byte runes may be split between reads
+		result = nil
+		decoder := New(iotest.OneByteReader(strings.NewReader(input)), opts...)
+		if err := decoder.Decode(&result); err != nil || !reflect.DeepEqual(result, expected) {
+			t.Errorf("Decode(%q) = %v, %v, expected %v", input, result, err, expected)
+		}
+
+		// The default whitespace is strict RFC 8259
+		if err := ValidateFragment([]byte(input)); err == nil {
+			t.Errorf("ValidateFragment(%q) without the option succeeded, expected error", input)
+		}
+	}
+
+	// Only the given runes are skipped
+	if err := ValidateFragment([]byte("[1,\u2003 2]"), opts...); err == nil {
+		t.Error("ValidateFragment accepted U+2003, which was not configured")
+	}
+	if err := ValidateFragment([]byte("[1,\u00a0 2]"), opts...); err != nil {
+		t.Errorf("ValidateFragment with U+00A0 failed: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Query extracts JSON from data like Unmarshal and returns the raw bytes of the value
+// at the RFC 6901 JSON Pointer, such as "/users/0/name", without decoding the rest.
+// The empty pointer refers to the whole extracted value
+func Query(data []byte, pointer string, opts ...Option) (json.RawMessage, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, newNoJSONError(position{}, "empty input data")
+	}
+	options := applyOptions(opts...)
+	if err := checkInputSize(data, options); err != nil {
+		return nil, err
+	}
+	data = prepareInput(data, options)
+
+	jsonBytes, _, _, err := parseSelected(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &materializer{data: jsonBytes}
+	for _, token := range tokens {
+		if err := m.enter(token); err != nil {
+			return nil, newInvalidJSONError(position{}, fmt.Sprintf("JSON pointer %s: %s", pointer, err))
+		}
+	}
+
+	m.skipWhitespace()
+	start := m.pos
+	if err := m.skipValue(); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(jsonBytes[start:m.pos]), nil
+}
+
+// parsePointer splits a JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, newInvalidJSONError(position{}, "JSON pointer must start with '/': "+pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// ~1 must be replaced before ~0 so that "~01" becomes "~1"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// enter moves to the member or element of the value at the current position named by token
+func (m *materializer) enter(token string) error {
+	m.skipWhitespace()
+	if m.pos >= len(m.data) {
+		return fmt.Errorf("unexpected end of JSON")
+	}
+
+	switch m.data[m.pos] {
+	case '{':
+		m.pos++
+		m.skipWhitespace()
+		if m.consume('}') {
+			return fmt.Errorf("key %q not found", token)
+		}
+		for {
+			m.skipWhitespace()
+			key, err := m.string()
+			if err != nil {
+				return err
+			}
+			m.skipWhitespace()
+			m.consume(':')
+			if key == token {
+				return nil
+			}
+			if err := m.skipValue(); err != nil {
+				return err
+			}
+			m.skipWhitespace()
+			if !m.consume(',') {
+				return fmt.Errorf("key %q not found", token)
+			}
+		}
+
+	case '[':
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+			return fmt.Errorf("invalid array index %q", token)
+		}
+		m.pos++
+		m.skipWhitespace()
+		if m.consume(']') {
+			return fmt.Errorf("index %d out of range", index)
+		}
+		for i := 0; i < index; i++ {
+			if err := m.skipValue(); err != nil {
+				return err
+			}
+			m.skipWhitespace()
+			if !m.consume(',') {
+				return fmt.Errorf("index %d out of range", index)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("cannot look up %q in a scalar value", token)
+	}
+}
@@ -0,0 +1,64 @@
+package jsonex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	blob := []byte(`2024-01-01 INFO request {"users": [{"name": "alice", "tags": ["a", "b"]}, {"name": "bob", "a/b": 1, "m~n": {"x": null}}], "count": 2, "": "empty"} done`)
+
+	tests := []struct {
+		pointer  string
+		expected string
+	}{
+		{"", `{"users":[{"name":"alice","tags":["a","b"]},{"name":"bob","a/b":1,"m~n":{"x":null}}],"count":2,"":"empty"}`},
+		{"/count", `2`},
+		{"/users/0/name", `"alice"`},
+		{"/users/0/tags", `["a","b"]`},
+		{"/users/0/tags/1", `"b"`},
+		{"/users/1", `{"name":"bob","a/b":1,"m~n":{"x":null}}`},
+		{"/users/1/a~1b", `1`},
+		{"/users/1/m~0n/x", `null`},
+		{"/", `"empty"`},
+	}
+
+	for _, test := range tests {
+		raw, err := Query(blob, test.pointer)
+		if err != nil {
+			t.Errorf("Query(%q) failed: %v", test.pointer, err)
+			continue
+		}
+		if string(raw) != test.expected {
+			t.Errorf("Query(%q) = %s, expected %s", test.pointer, raw, test.expected)
+		}
+	}
+}
+
+func TestQuery_Errors(t *testing.T) {
+	blob := []byte(`noise {"users": [{"name": "alice"}], "empty": {}, "list": []}`)
+
+	pointers := map[string]string{
+		"/missing":        `key "missing" not found`,
+		"/users/1":        "index 1 out of range",
+		"/users/-":        `invalid array index "-"`,
+		"/users/01":       `invalid array index "01"`,
+		"/users/0/age":    `key "age" not found`,
+		"/users/0/name/x": "scalar value",
+		"/empty/x":        `key "x" not found`,
+		"/list/0":         "index 0 out of range",
+		"users":           "must start with '/'",
+	}
+
+	for pointer, message := range pointers {
+		_, err := Query(blob, pointer)
+		jsonErr, ok := err.(*Error)
+		if !ok || jsonErr.Type != ErrInvalidJSON || !strings.Contains(jsonErr.Message, message) {
+			t.Errorf("Query(%q) = %v, expected error containing %q", pointer, err, message)
+		}
+	}
+
+	if _, err := Query([]byte(`no json here`), "/a"); err == nil {
+		t.Error("Expected error for input without JSON")
+	}
+}
@@ -585,7 +585,8 @@ func TestRFC8259_Interoperability(t *testing.T) {
 func TestRFC8259_CharacterEncoding(t *testing.T) {
 	// RFC 8259 Section 8.1: Character Encoding
 	// JSON text SHALL be encoded in UTF-8, UTF-16, or UTF-32
-	// Since Go strings are UTF-8, we test UTF-8 compliance
+	// UTF-8 compliance is tested here; UTF-16 and UTF-32 input is transcoded by
+	// WithAutoDetectEncoding and tested in encoding_test.go
 
 	tests := []struct {
 		name string
@@ -1,40 +1,141 @@
 package jsonex
 
 import (
+	"bytes"
+	"context"
 	"io"
+	"slices"
+	"strings"
+	"unicode/utf8"
 )
 
 // scanner handles low-level byte stream processing (unexported)
 type scanner struct {
-	reader io.Reader
-	buffer []byte
-	pos    int
-	size   int
-	line   int
-	column int
-	offset int
-	eof    bool
+	reader    io.Reader
+	buffer    []byte
+	bufferRef *[]byte // pooled reference to the read buffer, which unread may replace
+	pos       int
+	size      int
+	line      int
+	column    int
+	offset    int64
+	eof       bool
+
+	// readers to continue with after reader is exhausted
+	nextReaders    []io.Reader
+	atBoundary     bool // reader is exhausted and nextReaders are pending
+	valuePerReader bool // a value being recorded must not cross a reader boundary
+	perLine        bool // a value being recorded must not cross a newline
+
+	// bytes that may start a JSON value searched for by findJSONStart
+	starts string
+
+	// comments are skipped as whitespace
+	comments bool
+
+	// runes skipped as whitespace in addition to space, tab, line feed and carriage return
+	extraSpace []rune
+
+	// offset after which findJSONStart finds no first value, 0 once a value has been read
+	garbageLimit int64
+
+	// garbage skipped in the search for values is collected in skipped
+	keepSkipped bool
+	skipped     []byte
+
+	// a leading UTF-8 byte order mark has been looked for
+	bomChecked bool
+
+	// context of DecodeContext, checked before every read
+	ctx context.Context
+
+	// recording state used to replay bytes after a failed parse
+	recording bool
+	record    []byte
+	markPos   position
 }
 
 // newScanner creates a new scanner
 func newScanner(reader io.Reader, bufferSize int) *scanner {
+	buffer := getScannerBuffer(bufferSize)
 	return &scanner{
-		reader: reader,
-		buffer: make([]byte, bufferSize),
-		pos:    0,
-		size:   0,
-		line:   1,
-		column: 1,
-		offset: 0,
-		eof:    false,
+		reader:    reader,
+		buffer:    *buffer,
+		bufferRef: buffer,
+		pos:       0,
+		size:      0,
+		line:      1,
+		column:    1,
+		offset:    0,
+		eof:       false,
+		starts:    "{[",
 	}
 }
 
+// reset makes the scanner read from reader as a new input, keeping its buffers and
+// its configuration. A released scanner gets a read buffer of bufferSize bytes
+func (s *scanner) reset(reader io.Reader, bufferSize int) {
+	if s.bufferRef == nil {
+		s.bufferRef = getScannerBuffer(bufferSize)
+		s.buffer = *s.bufferRef
+	}
+	s.reader = reader
+	s.pos = 0
+	s.size = 0
+	s.setPosition(position{line: 1, column: 1})
+	s.eof = false
+	s.nextReaders = nil
+	s.atBoundary = false
+	s.bomChecked = false
+	s.ctx = nil
+	s.recording = false
+	s.record = s.record[:0]
+	s.skipped = s.skipped[:0]
+}
+
+// release returns the read buffer to the pool and ends the input, so that the
+// scanner reports io.EOF until it is reset
+func (s *scanner) release() {
+	if s.bufferRef != nil {
+		*s.bufferRef = s.buffer
+		putScannerBuffer(s.bufferRef)
+		s.buffer = nil
+		s.bufferRef = nil
+	}
+	s.pos = 0
+	s.size = 0
+	s.eof = true
+	s.nextReaders = nil
+	s.atBoundary = false
+	s.bomChecked = true
+	s.recording = false
+	s.skipped = s.skipped[:0]
+}
+
 // fillBuffer reads more data from the reader
 func (s *scanner) fillBuffer() error {
+	if !s.bomChecked {
+		s.bomChecked = true
+		if err := s.fillBuffer(); err != nil {
+			return err
+		}
+		if err := s.skipBOM(); err != nil {
+			return err
+		}
+		// Callers expect new data unless the input has ended
+		if s.pos >= s.size {
+			return s.fillBuffer()
+		}
+		return nil
+	}
 	if s.eof {
 		return io.EOF
 	}
+	if s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+	}
 
 	// Move remaining bytes to the beginning
 	if s.pos > 0 && s.pos < s.size {
@@ -46,10 +147,28 @@ func (s *scanner) fillBuffer() error {
 		s.pos = 0
 	}
 
+	// Switch to the next reader unless a value must not cross the boundary
+	if s.atBoundary {
+		if s.recording && s.valuePerReader {
+			return io.EOF
+		}
+		s.reader = s.nextReaders[0]
+		s.nextReaders = s.nextReaders[1:]
+		s.atBoundary = false
+	}
+
 	// Read new data
 	n, err := s.reader.Read(s.buffer[s.size:])
 	s.size += n
 
+	if err == io.EOF && len(s.nextReaders) > 0 {
+		s.atBoundary = true
+		if s.size == 0 {
+			return s.fillBuffer()
+		}
+		return nil
+	}
+
 	if err == io.EOF {
 		s.eof = true
 		if s.size == 0 {
@@ -60,6 +179,21 @@ func (s *scanner) fillBuffer() error {
 	return err
 }
 
+// skipBOM consumes a UTF-8 byte order mark at the start of the input, so that it is
+// not taken for garbage or a syntax error. Input that cannot start with the mark is
+// not waited for
+func (s *scanner) skipBOM() error {
+	for s.size-s.pos < len(utf8BOM) && bytes.HasPrefix(utf8BOM, s.buffer[s.pos:s.size]) && !s.eof && !s.atBoundary {
+		if err := s.fillBuffer(); err != nil {
+			return err
+		}
+	}
+	if bytes.HasPrefix(s.buffer[s.pos:s.size], utf8BOM) {
+		s.skip(utf8BOM)
+	}
+	return nil
+}
+
 // peek returns the current byte without advancing
 func (s *scanner) peek() (byte, error) {
 	if s.pos >= s.size {
@@ -86,19 +220,84 @@ func (s *scanner) next() (byte, error) {
 
 	b := s.buffer[s.pos]
 	s.pos++
-	s.offset++
+	s.advance(b)
 
-	// Update line and column tracking
-	if b == '\n' {
-		s.line++
-		s.column = 1
-	} else {
-		s.column++
+	if s.recording {
+		s.record = append(s.record, b)
 	}
 
 	return b, nil
 }
 
+// advance updates offset, line and column tracking for a consumed byte
+func (s *scanner) advance(b byte) {
+	s.setPosition(s.position().advance(b))
+}
+
+// setPosition overrides the current position tracking
+func (s *scanner) setPosition(pos position) {
+	s.offset = pos.offset
+	s.line = pos.line
+	s.column = pos.column
+}
+
+// mark starts recording consumed bytes so that they can be replayed by rewind
+func (s *scanner) mark() {
+	s.recording = true
+	s.record = s.record[:0]
+	s.markPos = s.position()
+}
+
+// unmark stops recording; the recorded bytes stay available until the next mark
+func (s *scanner) unmark() {
+	s.recording = false
+}
+
+// rewind stops recording and pushes the recorded bytes back into the scanner,
+// except for the first skip bytes which stay consumed
+func (s *scanner) rewind(skip int) {
+	replay := s.record
+	if skip > len(replay) {
+		skip = len(replay)
+	}
+
+	s.recording = false
+	s.setPosition(s.markPos)
+	for _, b := range replay[:skip] {
+		s.advance(b)
+	}
+	if s.keepSkipped {
+		s.skipped = append(s.skipped, replay[:skip]...)
+	}
+
+	s.unread(replay[skip:])
+	s.record = s.record[:0]
+}
+
+// unread puts data in front of the unconsumed bytes of the buffer
+func (s *scanner) unread(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	// Reuse the already consumed region when the data fits in front of pos
+	if len(data) <= s.pos {
+		s.pos -= len(data)
+		copy(s.buffer[s.pos:], data)
+		return
+	}
+
+	rest := s.buffer[s.pos:s.size]
+	size := len(data) + len(rest)
+	newBuffer := make([]byte, max(size, len(s.buffer)))
+	copy(newBuffer, data)
+	copy(newBuffer[len(data):], rest)
+
+	s.buffer = newBuffer
+	s.pos = 0
+	s.size = size
+}
+
 // position returns the current position
 func (s *scanner) position() position {
 	return position{
@@ -108,6 +307,38 @@ func (s *scanner) position() position {
 	}
 }
 
+// snippetSize is the number of bytes shown on each side of the position by snippet
+const snippetSize = 16
+
+// snippet returns the bytes around the current position for the context of an error,
+// such as `...{"a": 1,}`. Only buffered bytes are used, so it never reads
+func (s *scanner) snippet() string {
+	consumed := s.buffer[:s.pos]
+	if s.recording {
+		consumed = s.record
+	}
+	before := consumed[max(0, len(consumed)-snippetSize):]
+	after := s.buffer[s.pos:min(s.size, s.pos+snippetSize)]
+
+	var sb strings.Builder
+	if len(before) < len(consumed) {
+		sb.WriteString("...")
+	}
+	for _, b := range [][]byte{before, after} {
+		for _, c := range b {
+			// Keep the error message on one line
+			if c < 0x20 {
+				c = ' '
+			}
+			sb.WriteByte(c)
+		}
+	}
+	if s.pos+len(after) < s.size {
+		sb.WriteString("...")
+	}
+	return strings.ToValidUTF8(sb.String(), "\uFFFD")
+}
+
 // skipWhitespace skips whitespace characters (space, tab, newline, carriage return)
 func (s *scanner) skipWhitespace() error {
 	for {
@@ -115,8 +346,30 @@ func (s *scanner) skipWhitespace() error {
 		if err != nil {
 			return err
 		}
+		if b == '/' && s.comments {
+			if err := s.skipComment(); err != nil {
+				return err
+			}
+			continue
+		}
 		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
-			break
+			if len(s.extraSpace) == 0 {
+				break
+			}
+			n, err := s.extraSpaceLen(b)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			if err := s.discard(n); err != nil {
+				return err
+			}
+			continue
+		}
+		if b == '\n' && s.perLine && s.recording {
+			return newSyntaxError(s.position(), "value crosses line boundary")
 		}
 		_, err = s.next()
 		if err != nil {
@@ -126,29 +379,212 @@ func (s *scanner) skipWhitespace() error {
 	return nil
 }
 
-// findJSONStart searches for the start of a JSON object or array
-func (s *scanner) findJSONStart() (byte, error) {
-	for {
-		err := s.skipWhitespace()
+// extraSpaceLen returns the length of the rune starting with b if it is one of the extra
+// whitespace runes, or 0 otherwise
+func (s *scanner) extraSpaceLen(b byte) (int, error) {
+	r, size := rune(b), 1
+	if b >= utf8.RuneSelf {
+		data, err := s.peekBytes(utf8.UTFMax)
 		if err != nil {
 			return 0, err
 		}
+		r, size = utf8.DecodeRune(data)
+		if r == utf8.RuneError {
+			return 0, nil
+		}
+	}
+	if slices.Contains(s.extraSpace, r) {
+		return size, nil
+	}
+	return 0, nil
+}
 
-		b, err := s.peek()
-		if err != nil {
-			return 0, err
+// peekBytes returns up to n bytes without advancing, fewer only at the end of the input
+func (s *scanner) peekBytes(n int) ([]byte, error) {
+	for s.size-s.pos < n && !s.eof {
+		// A full buffer cannot take more bytes
+		if s.pos == 0 && s.size == len(s.buffer) {
+			break
 		}
+		if err := s.fillBuffer(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return s.buffer[s.pos:min(s.size, s.pos+n)], nil
+}
 
-		// Check for JSON start characters (only objects and arrays)
-		if b == '{' || b == '[' {
-			return b, nil
+// discard consumes n bytes
+func (s *scanner) discard(n int) error {
+	for i := 0; i < n; i++ {
+		if _, err := s.next(); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// Skip invalid characters and continue searching
-		_, err = s.next()
-		if err != nil {
-			return 0, err
+// skipComment skips the // line comment or /* */ block comment at the current byte
+func (s *scanner) skipComment() error {
+	pos := s.position()
+	if _, err := s.next(); err != nil {
+		return err
+	}
+	b, err := s.next()
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case '/':
+		// The newline is left to skipWhitespace
+		for {
+			b, err := s.peek()
+			if err == io.EOF || b == '\n' {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := s.next(); err != nil {
+				return err
+			}
+		}
+	case '*':
+		for prev := byte(0); ; prev = b {
+			if b, err = s.next(); err != nil {
+				return err
+			}
+			if prev == '*' && b == '/' {
+				return nil
+			}
+		}
+	default:
+		return newSyntaxError(pos, "unexpected character")
+	}
+}
+
+// findJSONStart searches for the start of a JSON object or array, or of any byte in starts
+// Buffered bytes are searched in bulk so that inputs without JSON are rejected quickly
+func (s *scanner) findJSONStart() (byte, error) {
+	if limit := s.garbageLimit; limit > 0 {
+		b, found, err := s.findJSONStartBefore(limit + 1)
+		if err == nil && !found {
+			err = errGarbagePrefix(int(limit))
+		}
+		return b, err
+	}
+
+	for {
+		if s.pos >= s.size {
+			if err := s.fillBuffer(); err != nil {
+				return 0, err
+			}
+			if s.pos >= s.size {
+				return 0, io.EOF
+			}
+		}
+
+		chunk := s.buffer[s.pos:s.size]
+		i := bytes.IndexAny(chunk, s.starts)
+		if i < 0 {
+			i = len(chunk)
+		}
+		s.skipGarbage(chunk[:i])
+
+		if i < len(chunk) {
+			return chunk[i], nil
 		}
 	}
 }
 
+// findJSONStartBefore is like findJSONStart but only searches up to the absolute
+// offset limit. It reports whether a start byte was found before the limit
+func (s *scanner) findJSONStartBefore(limit int64) (byte, bool, error) {
+	for s.offset < limit {
+		if s.pos >= s.size {
+			if err := s.fillBuffer(); err != nil {
+				return 0, false, err
+			}
+			if s.pos >= s.size {
+				return 0, false, io.EOF
+			}
+		}
+
+		chunk := s.buffer[s.pos : s.pos+int(min(int64(s.size-s.pos), limit-s.offset))]
+		i := bytes.IndexAny(chunk, s.starts)
+		if i < 0 {
+			i = len(chunk)
+		}
+		s.skipGarbage(chunk[:i])
+
+		if i < len(chunk) {
+			return chunk[i], true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// skipTo consumes input up to the next occurrence of marker, which is left buffered
+// at the current position. It returns io.EOF if marker does not occur
+func (s *scanner) skipTo(marker []byte) error {
+	for {
+		chunk := s.buffer[s.pos:s.size]
+		if i := bytes.Index(chunk, marker); i >= 0 {
+			s.skipGarbage(chunk[:i])
+			return nil
+		}
+
+		// Keep a partial marker at the end for the next read, unless the buffer is full
+		keep := min(len(marker)-1, len(chunk))
+		if s.pos == 0 && s.size == len(s.buffer) {
+			keep = min(keep, len(chunk)-1)
+		}
+		s.skipGarbage(chunk[:len(chunk)-keep])
+
+		if err := s.fillBuffer(); err != nil {
+			return err
+		}
+	}
+}
+
+// skipLine consumes input up to and including the next newline
+// Read errors are left for the next search to report
+func (s *scanner) skipLine() {
+	for {
+		if s.pos >= s.size {
+			if err := s.fillBuffer(); err != nil || s.pos >= s.size {
+				return
+			}
+		}
+
+		chunk := s.buffer[s.pos:s.size]
+		if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+			s.skipGarbage(chunk[:i+1])
+			return
+		}
+		s.skipGarbage(chunk)
+	}
+}
+
+// skipGarbage is like skip for bytes that are not part of a value, which are
+// collected if keepSkipped is set
+func (s *scanner) skipGarbage(data []byte) {
+	if s.keepSkipped {
+		s.skipped = append(s.skipped, data...)
+	}
+	s.skip(data)
+}
+
+// skip consumes buffered bytes without recording them
+func (s *scanner) skip(data []byte) {
+	s.pos += len(data)
+	s.offset += int64(len(data))
+	if lines := bytes.Count(data, []byte{'\n'}); lines > 0 {
+		s.line += lines
+		s.column = 1 + columnsIn(data[bytes.LastIndexByte(data, '\n')+1:])
+	} else {
+		s.column += columnsIn(data)
+	}
+}
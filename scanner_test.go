@@ -141,4 +141,25 @@ func TestScanner_DecoderStreaming(t *testing.T) {
 	if obj2["final"] != true {
 		t.Errorf("Final object incorrect: %v", obj2)
 	}
-}
\ No newline at end of file
+}
+func TestScanner_OffsetBeyond2GB(t *testing.T) {
+	// Start as if 2^31 bytes had already been consumed from the stream
+	const base = int64(1) << 31
+	p := newParser(strings.NewReader(`ab {"a": x}`), defaultOptions())
+	p.scanner.offset = base
+
+	_, err := p.parseNext()
+	jsonErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+	if expected := base + 9; jsonErr.Position.Offset != expected {
+		t.Errorf("Error offset = %d, expected %d", jsonErr.Position.Offset, expected)
+	}
+
+	// The position type keeps the full offset on every platform
+	pos := position{offset: base}.advance('x')
+	if pos.toPublic().Offset != base+1 {
+		t.Errorf("Public offset = %d, expected %d", pos.toPublic().Offset, base+1)
+	}
+}
@@ -0,0 +1,444 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Token holds a value of one of these types, as in encoding/json:
+// Delim for the four JSON delimiters, bool for booleans, float64 for numbers (or
+// json.Number and int64 as the number options decide), string for strings and object
+// keys, and nil for null
+type Token = json.Token
+
+// Delim is a JSON object or array delimiter, one of { } [ or ]
+type Delim = json.Delim
+
+// tokenFrame is an object or array opened by Token and not closed yet
+type tokenFrame struct {
+	delim byte            // '{' or '['
+	count int             // entries started so far
+	keys  map[string]bool // keys seen, tracked only to reject duplicates
+}
+
+// closing returns the delimiter that closes the frame
+func (f *tokenFrame) closing() byte {
+	if f.delim == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+// Token returns the next JSON token in the input stream: a delimiter, an object key or
+// a scalar value. It returns io.EOF once no further value starts in the input.
+// Like Decode, it skips garbage between top-level values, but inside a value the input
+// must be valid JSON; after an error the search resumes at the failing byte.
+// Token and Decode may be mixed: after Token returns the '[' of a large array, each
+// element can be decoded with Decode while More reports true
+func (d *Decoder) Token() (Token, error) {
+	if err := d.err; err != nil {
+		d.err = nil
+		return nil, err
+	}
+
+	token, err := d.nextToken()
+	if err != nil {
+		d.resetTokens()
+	}
+	return token, err
+}
+
+// nextToken reads the next token according to the parser state
+func (d *Decoder) nextToken() (Token, error) {
+	p := d.parser
+	for {
+		if len(d.tokens) == 0 {
+			b, err := p.scanner.findJSONStart()
+			if err != nil {
+				return nil, err
+			}
+			token, err := d.tokenValue(b)
+			if err == nil {
+				d.takeSkipped()
+			}
+			return token, err
+		}
+
+		b, err := d.peekToken()
+		if err != nil {
+			return nil, err
+		}
+		frame := &d.tokens[len(d.tokens)-1]
+
+		switch p.state {
+		case stateObjectStart, stateArrayStart:
+			if b == frame.closing() {
+				return d.closeToken()
+			}
+			if frame.delim == '{' {
+				p.state = stateObjectKey
+			} else {
+				p.state = stateArrayValue
+			}
+		case stateObjectKey:
+			// Only a comma leads here with the closing brace next
+			if b == '}' && p.options.trailingCommas {
+				return d.closeToken()
+			}
+			return d.tokenKey()
+		case stateObjectColon:
+			if b != ':' {
+				return nil, newSyntaxError(p.scanner.position(), "expected ':', found "+quoteByte(b), p.scanner.snippet())
+			}
+			if err := d.acceptToken(stateObjectValue); err != nil {
+				return nil, err
+			}
+		case stateArrayValue:
+			if b == ']' && p.options.trailingCommas {
+				return d.closeToken()
+			}
+			return d.tokenValue(b)
+		case stateObjectValue:
+			return d.tokenValue(b)
+		case stateObjectComma, stateArrayComma:
+			if b == frame.closing() {
+				return d.closeToken()
+			}
+			if err := d.acceptComma(b, frame); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, newSyntaxError(p.scanner.position(), "unexpected token state: "+p.state.String())
+		}
+	}
+}
+
+// peekToken skips whitespace and returns the next byte inside a value
+func (d *Decoder) peekToken() (byte, error) {
+	s := d.parser.scanner
+	if err := s.skipWhitespace(); err != nil {
+		return 0, d.valueError(err)
+	}
+	b, err := s.peek()
+	if err != nil {
+		return 0, d.valueError(err)
+	}
+	return b, nil
+}
+
+// valueError turns the end of the input inside a value into an ErrEOF error
+func (d *Decoder) valueError(err error) error {
+	if err == io.EOF {
+		return newEOFError(d.parser.scanner.position(), "unexpected end of input in JSON value")
+	}
+	return err
+}
+
+// acceptToken consumes the peeked byte and moves to state
+func (d *Decoder) acceptToken(state parseState) error {
+	if _, err := d.parser.scanner.next(); err != nil {
+		return d.valueError(err)
+	}
+	d.parser.state = state
+	return nil
+}
+
+// acceptComma consumes the comma b between the entries of frame
+func (d *Decoder) acceptComma(b byte, frame *tokenFrame) error {
+	p := d.parser
+	if b != ',' {
+		return newSyntaxError(p.scanner.position(), fmt.Sprintf("expected ',' or '%c', found %s", frame.closing(), quoteByte(b)), p.scanner.snippet())
+	}
+	if frame.delim == '{' {
+		return d.acceptToken(stateObjectKey)
+	}
+	return d.acceptToken(stateArrayValue)
+}
+
+// countToken counts a new entry of the innermost object or array against its limit
+func (d *Decoder) countToken() error {
+	if len(d.tokens) == 0 {
+		return nil
+	}
+	frame := &d.tokens[len(d.tokens)-1]
+	frame.count++
+	if frame.delim == '{' {
+		return d.parser.checkCount(frame.count, d.options.maxObjectKeys, "maximum object keys exceeded")
+	}
+	return d.parser.checkCount(frame.count, d.options.maxArrayElems, "maximum array elements exceeded")
+}
+
+// tokenValue reads the value starting with the peeked byte b, which is a delimiter
+// for objects and arrays and the whole value for scalars
+func (d *Decoder) tokenValue(b byte) (Token, error) {
+	p := d.parser
+	if p.state != stateObjectValue {
+		if err := d.countToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	if b == '{' || b == '[' {
+		if len(d.tokens) >= p.options.maxDepth {
+			return nil, newSyntaxError(p.scanner.position(), "maximum nesting depth exceeded")
+		}
+		frame := tokenFrame{delim: b}
+		state := stateArrayStart
+		if b == '{' {
+			state = stateObjectStart
+			if p.options.disallowDupKeys {
+				frame.keys = make(map[string]bool)
+			}
+		}
+		if err := d.acceptToken(state); err != nil {
+			return nil, err
+		}
+		d.tokens = append(d.tokens, frame)
+		return Delim(b), nil
+	}
+
+	pos := p.scanner.position()
+	buf := p.getBuffer()
+	defer putBuffer(buf)
+	err := p.parseElementFrom(b, buf)
+	if err == nil {
+		err = buf.err
+	}
+	if err != nil {
+		return nil, d.valueError(err)
+	}
+
+	token, err := d.scalarToken(buf.bytes(), pos)
+	if err != nil {
+		return nil, err
+	}
+	d.endTokenValue()
+	return token, nil
+}
+
+// scalarToken converts a scalar extracted by the parser to its token
+func (d *Decoder) scalarToken(data []byte, pos position) (Token, error) {
+	switch data[0] {
+	case '"':
+		s, err := processEscape(data[1 : len(data)-1])
+		if err != nil {
+			return nil, escapeErrorAt(err, pos)
+		}
+		return string(s), nil
+	case 't':
+		return true, nil
+	case 'f':
+		return false, nil
+	case 'n':
+		return nil, nil
+	}
+
+	token := string(data)
+	if convert := numberConverter(d.options); convert != nil {
+		return convert(token)
+	}
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, newSyntaxError(pos, "invalid number: "+token)
+	}
+	return f, nil
+}
+
+// tokenKey reads an object key
+func (d *Decoder) tokenKey() (Token, error) {
+	p := d.parser
+	if err := d.countToken(); err != nil {
+		return nil, err
+	}
+
+	pos := p.scanner.position()
+	buf := p.getBuffer()
+	defer putBuffer(buf)
+	err := p.parseString(buf)
+	if err == nil {
+		err = buf.err
+	}
+	if err != nil {
+		return nil, d.valueError(err)
+	}
+
+	data := buf.bytes()
+	key, err := processEscape(data[1 : len(data)-1])
+	if err != nil {
+		return nil, escapeErrorAt(err, pos)
+	}
+	if keys := d.tokens[len(d.tokens)-1].keys; keys != nil {
+		if keys[string(key)] {
+			return nil, newSyntaxError(pos, fmt.Sprintf("duplicate key %q", key))
+		}
+		keys[string(key)] = true
+	}
+	p.state = stateObjectColon
+	return string(key), nil
+}
+
+// closeToken consumes the peeked delimiter closing the innermost object or array
+func (d *Decoder) closeToken() (Token, error) {
+	closing := d.tokens[len(d.tokens)-1].closing()
+	if _, err := d.parser.scanner.next(); err != nil {
+		return nil, d.valueError(err)
+	}
+	d.tokens = d.tokens[:len(d.tokens)-1]
+	d.endTokenValue()
+	return Delim(closing), nil
+}
+
+// endTokenValue moves past a complete value inside the objects and arrays opened by Token
+func (d *Decoder) endTokenValue() {
+	p := d.parser
+	switch {
+	case len(d.tokens) == 0:
+		p.state = stateEnd
+	case d.tokens[len(d.tokens)-1].delim == '{':
+		p.state = stateObjectComma
+	default:
+		p.state = stateArrayComma
+	}
+}
+
+// resetTokens abandons the objects and arrays opened by Token, so that the next call
+// searches for a new value
+func (d *Decoder) resetTokens() {
+	d.tokens = d.tokens[:0]
+	d.parser.state = stateValue
+}
+
+// prepareTokenValue moves past the comma or colon before the next value inside the
+// objects and arrays opened by Token and returns the first byte of the value
+func (d *Decoder) prepareTokenValue() (byte, error) {
+	p := d.parser
+	for {
+		b, err := d.peekToken()
+		if err != nil {
+			return 0, err
+		}
+		frame := &d.tokens[len(d.tokens)-1]
+
+		switch p.state {
+		case stateArrayStart:
+			p.state = stateArrayValue
+		case stateArrayComma:
+			if err := d.acceptComma(b, frame); err != nil {
+				return 0, err
+			}
+		case stateObjectColon:
+			if b != ':' {
+				return 0, newSyntaxError(p.scanner.position(), "expected ':', found "+quoteByte(b), p.scanner.snippet())
+			}
+			if err := d.acceptToken(stateObjectValue); err != nil {
+				return 0, err
+			}
+		case stateArrayValue, stateObjectValue:
+			return b, nil
+		default:
+			return 0, newSyntaxError(p.scanner.position(), "Decode called when not at a value: "+p.state.String())
+		}
+	}
+}
+
+// decodeToken decodes the next value inside the objects and arrays opened by Token
+func (d *Decoder) decodeToken(v interface{}) error {
+	p := d.parser
+	b, err := d.prepareTokenValue()
+	if err != nil {
+		return err
+	}
+	if p.state == stateArrayValue {
+		if err := d.countToken(); err != nil {
+			return err
+		}
+	}
+
+	p.depth = len(d.tokens)
+	buf := p.getValueBuffer()
+	defer putBuffer(buf)
+
+	// Record the source bytes for raw messages
+	p.scanner.mark()
+	err = p.parseElementFrom(b, buf)
+	p.scanner.unmark()
+	if err == nil {
+		err = buf.err
+	}
+	if err != nil {
+		return d.valueError(err)
+	}
+
+	d.endTokenValue()
+	return decodeSource(p.scanner.record, buf.bytes(), v, d.options)
+}
+
+// DecodeArray decodes the elements of the next JSON array one at a time into the value
+// pointed to by elem, which is reset to its zero value before each element, and calls
+// fn after each one. Only one element is held in memory at a time, so arrays larger
+// than memory can be processed. Garbage before the array is skipped like by Decode,
+// and the array may also be a value inside an object or array opened by Token.
+// An error from fn stops the iteration and is returned; the rest of the array can then
+// be read with Token or Decode. DecodeArray returns io.EOF if no value remains, and an
+// error without consuming the value if it is not an array
+func (d *Decoder) DecodeArray(elem interface{}, fn func() error) error {
+	target := reflect.ValueOf(elem)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return wrapDecodeError(&json.InvalidUnmarshalError{Type: reflect.TypeOf(elem)})
+	}
+
+	var b byte
+	var err error
+	if len(d.tokens) == 0 {
+		b, err = d.parser.scanner.findJSONStart()
+	} else {
+		b, err = d.prepareTokenValue()
+	}
+	if err != nil {
+		d.resetTokens()
+		return err
+	}
+	if b != '[' {
+		return newSyntaxError(d.parser.scanner.position(), "expected '[', found "+quoteByte(b), d.parser.scanner.snippet())
+	}
+	if _, err := d.Token(); err != nil {
+		return err
+	}
+
+	for d.More() {
+		target.Elem().SetZero()
+		if err := d.Decode(elem); err != nil {
+			return err
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	// The closing bracket, or the error that made More report false
+	_, err = d.Token()
+	return err
+}
+
+// moreTokens reports whether another entry follows in the innermost object or array
+// opened by Token. A trailing comma allowed by WithTrailingCommas is consumed
+func (d *Decoder) moreTokens() bool {
+	p := d.parser
+	b, err := d.peekToken()
+	if err != nil {
+		return false
+	}
+	frame := &d.tokens[len(d.tokens)-1]
+	if b == ',' && p.options.trailingCommas && (p.state == stateArrayComma || p.state == stateObjectComma) {
+		if d.acceptComma(b, frame) != nil {
+			return false
+		}
+		if b, err = d.peekToken(); err != nil {
+			return false
+		}
+	}
+	return b != frame.closing()
+}
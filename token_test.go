@@ -0,0 +1,290 @@
+package jsonex
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readTokens reads tokens until an error and returns them with that error
+func readTokens(decoder interface{ Token() (Token, error) }) ([]Token, error) {
+	var tokens []Token
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+func TestDecoder_Token(t *testing.T) {
+	input := `noise {"a": [1, "x\ny", true, null, {"bé": -2.5e1}], "c": {}} garbage [3, []] end`
+	tokens, err := readTokens(New(strings.NewReader(input)))
+	if err != io.EOF {
+		t.Fatalf("Token returned %v, expected io.EOF", err)
+	}
+
+	expected := []Token{
+		Delim('{'), "a", Delim('['), 1.0, "x\ny", true, nil, Delim('{'), "bé", -25.0, Delim('}'), Delim(']'),
+		"c", Delim('{'), Delim('}'), Delim('}'),
+		Delim('['), 3.0, Delim('['), Delim(']'), Delim(']'),
+	}
+	if !reflect.DeepEqual(tokens, expected) {
+		t.Errorf("Token returned %v, expected %v", tokens, expected)
+	}
+}
+
+func TestDecoder_TokenMatchesStdlib(t *testing.T) {
+	inputs := []string{
+		`{"id": 1, "tags": ["a", "b\"c", "😀"], "meta": {"ok": false, "n": null}}`,
+		`[[], {}, [[1e3]], {"": ""}, -0.125]`,
+		`[1, 2] {"a": 3}`,
+	}
+
+	for _, input := range inputs {
+		expected, err := readTokens(json.NewDecoder(strings.NewReader(input)))
+		if err != io.EOF {
+			t.Fatalf("json.Decoder.Token(%s) failed: %v", input, err)
+		}
+		tokens, err := readTokens(New(strings.NewReader(input)))
+		if err != io.EOF || !reflect.DeepEqual(tokens, expected) {
+			t.Errorf("Token(%s) = %v, %v, expected %v", input, tokens, err, expected)
+		}
+	}
+}
+
+func TestDecoder_TokenWithDecode(t *testing.T) {
+	decoder := New(strings.NewReader(`log: {"items": [{"id": 1}, {"id": 2, "raw": [ 1 ]}, {"id": 3}], "total": 3} tail`))
+
+	expectToken := func(expected Token) {
+		t.Helper()
+		token, err := decoder.Token()
+		if err != nil || token != expected {
+			t.Fatalf("Token = %v, %v, expected %v", token, err, expected)
+		}
+	}
+	expectToken(Delim('{'))
+	expectToken("items")
+	expectToken(Delim('['))
+
+	type item struct {
+		ID  int             `json:"id"`
+		Raw json.RawMessage `json:"raw"`
+	}
+	var items []item
+	for decoder.More() {
+		var it item
+		if err := decoder.Decode(&it); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		items = append(items, it)
+	}
+	if len(items) != 3 || items[0].ID != 1 || items[2].ID != 3 || string(items[1].Raw) != `[ 1 ]` {
+		t.Errorf("Decode returned %+v", items)
+	}
+
+	expectToken(Delim(']'))
+	expectToken("total")
+
+	// Decode also reads object values after their key
+	var total int
+	if err := decoder.Decode(&total); err != nil || total != 3 {
+		t.Errorf("Decode = %d, %v, expected 3", total, err)
+	}
+	if decoder.More() {
+		t.Error("More = true at the end of the object")
+	}
+	expectToken(Delim('}'))
+	if token, err := decoder.Token(); err != io.EOF {
+		t.Errorf("Token at end = %v, %v, expected io.EOF", token, err)
+	}
+
+	// Object keys must be read with Token
+	decoder = New(strings.NewReader(`{"a": 1}`))
+	expectToken(Delim('{'))
+	var v interface{}
+	if err := decoder.Decode(&v); err == nil {
+		t.Errorf("Decode of an object key = %v, expected error", v)
+	}
+}
+
+func TestDecoder_TokenErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    []Option
+		errType ErrorType
+		offset  int64
+		message string
+	}{
+		{"Missing comma", `[1 2]`, nil, ErrSyntax, 3, "expected ',' or ']', found '2'"},
+		{"Missing colon", `{"a" 1}`, nil, ErrSyntax, 5, "expected ':', found '1'"},
+		{"Key not a string", `{1: 2}`, nil, ErrSyntax, 1, "expected '\"', found '1'"},
+		{"Trailing comma", `[1, ]`, nil, ErrSyntax, 4, "unexpected character ']'"},
+		{"Truncated", `{"a": [1, 2`, nil, ErrEOF, 11, "unexpected end of input in JSON value"},
+		{"Depth limit", `[[[1]]]`, []Option{WithMaxDepth(2)}, ErrSyntax, 2, "maximum nesting depth exceeded"},
+		{"Array limit", `[1, 2, 3]`, []Option{WithMaxArrayElements(2)}, ErrSyntax, 7, "maximum array elements exceeded"},
+		{"Duplicate key", `{"a": 1, "a": 2}`, []Option{WithDisallowDuplicateKeys()}, ErrSyntax, 9, `duplicate key "a"`},
+		{"Lone surrogate", `[1, "a\udc00b"]`, nil, ErrEscape, 4, "unexpected low surrogate"},
+		{"Lone surrogate key", `{"\ud800": 1}`, nil, ErrEscape, 1, "incomplete surrogate pair"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readTokens(New(strings.NewReader(test.input), test.opts...))
+			var jsonErr *Error
+			if !errors.As(err, &jsonErr) {
+				t.Fatalf("Token returned %v, expected *Error", err)
+			}
+			if jsonErr.Type != test.errType || jsonErr.Position.Offset != test.offset || jsonErr.Message != test.message {
+				t.Errorf("Token returned %v, expected %v at offset %d: %s", err, test.errType, test.offset, test.message)
+			}
+		})
+	}
+
+	// After an error the search for the next value resumes at the failing byte
+	decoder := New(strings.NewReader(`[1 2] {"b": 2}`))
+	tokens, err := readTokens(decoder)
+	if err == nil || err == io.EOF || !reflect.DeepEqual(tokens, []Token{Delim('['), 1.0}) {
+		t.Fatalf("Token returned %v, %v, expected a syntax error after [1", tokens, err)
+	}
+	tokens, err = readTokens(decoder)
+	if err != io.EOF || !reflect.DeepEqual(tokens, []Token{Delim('{'), "b", 2.0, Delim('}')}) {
+		t.Errorf("Token after error returned %v, %v", tokens, err)
+	}
+}
+
+func TestDecoder_TokenOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected []Token
+	}{
+		{"UseNumber", `[1.50, 2]`, []Option{WithUseNumber()}, []Token{Delim('['), json.Number("1.50"), json.Number("2"), Delim(']')}},
+		{"IntegerNumbers", `[1.5, 2]`, []Option{WithIntegerNumbers(true)}, []Token{Delim('['), 1.5, int64(2), Delim(']')}},
+		{"Trailing commas", `{"a": [1, ], }`, []Option{WithAllowTrailingCommas()}, []Token{Delim('{'), "a", Delim('['), 1.0, Delim(']'), Delim('}')}},
+		{"Comments", `[1, /* two */ 2] // end`, []Option{WithAllowComments()}, []Token{Delim('['), 1.0, 2.0, Delim(']')}},
+		{"Lenient literals", `[True, None]`, []Option{WithLenientLiterals(true)}, []Token{Delim('['), true, nil, Delim(']')}},
+		{"Scalars", `log "a" 1 [true]`, []Option{WithAllowScalars(true)}, []Token{"a", 1.0, Delim('['), true, Delim(']')}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := readTokens(New(strings.NewReader(test.input), test.opts...))
+			if err != io.EOF || !reflect.DeepEqual(tokens, test.expected) {
+				t.Errorf("Token returned %v, %v, expected %v", tokens, err, test.expected)
+			}
+		})
+	}
+}
+
+func TestDecoder_DecodeArray(t *testing.T) {
+	decoder := New(strings.NewReader(`log: [{"a": 1}, {"b": 2}, {}] tail`))
+	var elem map[string]int
+	var results []map[string]int
+	if err := decoder.DecodeArray(&elem, func() error {
+		results = append(results, elem)
+		return nil
+	}); err != nil {
+		t.Fatalf("DecodeArray failed: %v", err)
+	}
+	// The element is reset, so keys of one element do not leak into the next
+	expected := []map[string]int{{"a": 1}, {"b": 2}, {}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("DecodeArray decoded %v, expected %v", results, expected)
+	}
+	if err := decoder.DecodeArray(&elem, func() error { return nil }); err != io.EOF {
+		t.Errorf("DecodeArray at end = %v, expected io.EOF", err)
+	}
+
+	// An array inside a value opened by Token
+	decoder = New(strings.NewReader(`{"items": [1, 2, 3], "n": []}`))
+	for _, expected := range []Token{Delim('{'), "items"} {
+		if token, err := decoder.Token(); err != nil || token != expected {
+			t.Fatalf("Token = %v, %v, expected %v", token, err, expected)
+		}
+	}
+	var n int
+	sum := 0
+	if err := decoder.DecodeArray(&n, func() error { sum += n; return nil }); err != nil || sum != 6 {
+		t.Errorf("DecodeArray = %v with sum %d, expected sum 6", err, sum)
+	}
+	tokens, err := readTokens(decoder)
+	if err != io.EOF || !reflect.DeepEqual(tokens, []Token{"n", Delim('['), Delim(']'), Delim('}')}) {
+		t.Errorf("Token after DecodeArray returned %v, %v", tokens, err)
+	}
+
+	// An error from fn stops the iteration, and the rest of the array can still be read
+	stop := errors.New("stop")
+	decoder = New(strings.NewReader(`[1, 2, 3]`))
+	if err := decoder.DecodeArray(&n, func() error { return stop }); err != stop {
+		t.Errorf("DecodeArray = %v, expected %v", err, stop)
+	}
+	tokens, err = readTokens(decoder)
+	if err != io.EOF || !reflect.DeepEqual(tokens, []Token{2.0, 3.0, Delim(']')}) {
+		t.Errorf("Token after stopping returned %v, %v", tokens, err)
+	}
+
+	// A value that is not an array is left for Decode
+	decoder = New(strings.NewReader(`{"a": 1}`))
+	if err := decoder.DecodeArray(&n, func() error { return nil }); err == nil {
+		t.Error("DecodeArray of an object succeeded")
+	}
+	var object map[string]int
+	if err := decoder.Decode(&object); err != nil || object["a"] != 1 {
+		t.Errorf("Decode after DecodeArray = %v, %v", object, err)
+	}
+
+	// A truncated array is reported after its complete elements
+	decoder = New(strings.NewReader(`[1, 2`))
+	count := 0
+	err = decoder.DecodeArray(&n, func() error { count++; return nil })
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Type != ErrEOF || count != 2 {
+		t.Errorf("DecodeArray = %v after %d elements, expected ErrEOF after 2", err, count)
+	}
+
+	if err := New(strings.NewReader(`[1]`)).DecodeArray(nil, func() error { return nil }); err == nil {
+		t.Error("DecodeArray into nil succeeded")
+	}
+}
+
+func TestDecoder_DecodeArrayBoundedMemory(t *testing.T) {
+	// An array of about 4MB streamed from a pipe is processed element by element
+	const elements = 100000
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte("stream: ["))
+		for i := 0; i < elements; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte(`{"id": 1, "name": "element"}`))
+		}
+		w.Write([]byte("]"))
+		w.Close()
+	}()
+
+	decoder := New(r)
+	var elem struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	count := 0
+	if err := decoder.DecodeArray(&elem, func() error {
+		count++
+		return nil
+	}); err != nil {
+		t.Fatalf("DecodeArray failed: %v", err)
+	}
+	if count != elements {
+		t.Errorf("DecodeArray decoded %d elements, expected %d", count, elements)
+	}
+	if size := cap(decoder.parser.scanner.record) + len(decoder.parser.scanner.buffer); size > 64*1024 {
+		t.Errorf("Decoder holds %d bytes, expected the size of one element and the read buffer", size)
+	}
+}
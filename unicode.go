@@ -4,13 +4,8 @@ import (
 	"unicode/utf8"
 )
 
-// validateUTF8 checks if the given byte slice contains valid UTF-8
-func validateUTF8(data []byte) error {
-	if !utf8.Valid(data) {
-		return newUnicodeError(position{}, "invalid UTF-8 sequence")
-	}
-	return nil
-}
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
 
 // decodeSurrogatePair converts a UTF-16 surrogate pair to a Unicode code point
 func decodeSurrogatePair(high, low rune) rune {
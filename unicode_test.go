@@ -5,26 +5,6 @@ import (
 	"unicode/utf8"
 )
 
-func TestValidateUTF8(t *testing.T) {
-	tests := []struct {
-		input    []byte
-		expected bool
-	}{
-		{[]byte("hello"), true},
-		{[]byte("こんにちは"), true},
-		{[]byte("🙂"), true},
-		{[]byte{0xff, 0xfe}, false}, // Invalid UTF-8
-		{[]byte{0xc0, 0x80}, false}, // Overlong encoding
-	}
-
-	for _, test := range tests {
-		err := validateUTF8(test.input)
-		if (err == nil) != test.expected {
-			t.Errorf("validateUTF8(%v) error = %v, expected valid = %v", test.input, err, test.expected)
-		}
-	}
-}
-
 func TestDecodeSurrogatePair(t *testing.T) {
 	// Test valid surrogate pair (😀 emoji)
 	high := rune(0xD83D)
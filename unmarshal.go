@@ -3,6 +3,12 @@ package jsonex
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 // Unmarshal parses the JSON-encoded data and stores the result in the value pointed to by v
@@ -10,31 +16,386 @@ import (
 // object or array from the input data, ignoring any preceding or trailing invalid content
 func Unmarshal(data []byte, v interface{}, opts ...Option) error {
 	if len(data) == 0 {
-		return newInvalidJSONError(position{}, "empty input data")
+		return newNoJSONError(position{}, "empty input data")
 	}
 
 	options := applyOptions(opts...)
+	if err := checkInputSize(data, options); err != nil {
+		return err
+	}
+	data = prepareInput(data, options)
+
+	_, _, err := unmarshal(data, v, options)
+	return err
+}
+
+// UnmarshalAt is like Unmarshal but also returns the offsets of the extracted JSON in
+// data, so that data[start:end] runs from its opening '{' or '[' to the matching closing
+// one. WithCSVQuoting is not supported because it changes offsets
+func UnmarshalAt(data []byte, v interface{}, opts ...Option) (start, end int, err error) {
+	if len(data) == 0 {
+		return 0, 0, newNoJSONError(position{}, "empty input data")
+	}
+
+	options := applyOptions(opts...)
+	if options.csvQuoting {
+		return 0, 0, newInvalidJSONError(position{}, "UnmarshalAt does not support CSV quoting")
+	}
+	if options.autoEncoding {
+		return 0, 0, newInvalidJSONError(position{}, "UnmarshalAt does not support encoding detection")
+	}
+	if err := checkInputSize(data, options); err != nil {
+		return 0, 0, err
+	}
+
+	return unmarshal(data, v, options)
+}
 
+// unmarshal extracts the selected JSON value in data into v and returns its source offsets
+func unmarshal(data []byte, v interface{}, options options) (start, end int, err error) {
 	// Fast path: try standard library first if data looks clean and no special options
-	if options.maxDepth == 1000 && options.bufferSize == 4096 { // Default options only
-		trimmed := bytes.TrimSpace(data)
-		if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
-			// Check if the trimmed data equals the original data (no garbage)
-			if bytes.Equal(trimmed, data) {
-				if err := json.Unmarshal(trimmed, v); err == nil {
-					return nil
+	if options.allowsFastPath() {
+		// The robust path skips a leading byte order mark as garbage, so the fast path skips it too
+		body := bytes.TrimPrefix(data, utf8BOM)
+		trimmed := bytes.TrimSpace(body)
+		// The standard library silently replaces invalid UTF-8, so strict mode must take the robust path
+		if len(trimmed) > 0 && strings.IndexByte(options.startBytes(), trimmed[0]) >= 0 && (!options.strictUTF8 || utf8.Valid(trimmed)) {
+			// Check if the trimmed data equals the original data (no garbage), apart from
+			// a byte order mark within the WithMaxGarbagePrefix limit
+			if bytes.Equal(trimmed, body) && (options.maxGarbage == 0 || len(data)-len(body) <= options.maxGarbage) {
+				if err := decode(trimmed, v, options); err == nil {
+					return len(data) - len(body), len(data), nil
 				}
 			}
 		}
 	}
 
-	// Robust path: find and extract the longest valid JSON
-	jsonBytes, err := parseLongest(data, options)
+	// Robust path: find and extract the selected (by default the longest) valid JSON
+	jsonBytes, start, end, err := parseSelected(data, options)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 
 	// Use standard library to decode the extracted JSON
 	// The standard library already handles all RFC 8259 compliant escape sequences
-	return json.Unmarshal(jsonBytes, v)
+	return start, end, decodeSource(data[start:end], jsonBytes, v, options)
+}
+
+// UnmarshalFrom decodes the first valid JSON object or array that starts at or after
+// offset in data and returns the offset just past it, so that a large buffer can be
+// processed value by value and resumed from a saved offset. io.EOF is returned when
+// no object or array starts after offset. WithCSVQuoting is not supported because
+// it changes offsets
+func UnmarshalFrom(data []byte, offset int, v interface{}, opts ...Option) (next int, err error) {
+	if offset < 0 || offset > len(data) {
+		return offset, newInvalidJSONError(position{offset: int64(offset)}, "offset out of range")
+	}
+
+	options := applyOptions(opts...)
+	if options.csvQuoting {
+		return offset, newInvalidJSONError(position{}, "UnmarshalFrom does not support CSV quoting")
+	}
+	if options.autoEncoding {
+		return offset, newInvalidJSONError(position{}, "UnmarshalFrom does not support encoding detection")
+	}
+	if err := checkInputSize(data, options); err != nil {
+		return offset, err
+	}
+
+	rest := data[offset:]
+	if bytes.IndexAny(rest, options.startBytes()) < 0 {
+		return len(data), io.EOF
+	}
+
+	jsonBytes, start, end, err := parseSequential(rest, options, true)
+	if err != nil {
+		return offset, err
+	}
+	return offset + end, decodeSource(rest[start:end], jsonBytes, v, options)
+}
+
+// Extract returns the JSON value that Unmarshal would decode, by default the longest one,
+// without decoding it. The bytes are copied from the input, so they keep its formatting
+// and do not alias it
+func Extract(data []byte, opts ...Option) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, newNoJSONError(position{}, "empty input data")
+	}
+
+	options := applyOptions(opts...)
+	if err := checkInputSize(data, options); err != nil {
+		return nil, err
+	}
+	data = prepareInput(data, options)
+
+	jsonBytes, start, end, err := parseSelected(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// The source bytes are invalid when the parser rewrote the value, as WithWindowsPathStrings does
+	if source := data[start:end]; json.Valid(source) {
+		jsonBytes = source
+	}
+	return append(json.RawMessage(nil), jsonBytes...), nil
+}
+
+// UnmarshalAll extracts every valid JSON object or array in data from left to right.
+// Values do not overlap: after a value is extracted, the search resumes at its end,
+// so values nested in it are not returned separately
+func UnmarshalAll(data []byte, opts ...Option) ([]json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, newNoJSONError(position{}, "empty input data")
+	}
+
+	options := applyOptions(opts...)
+	if err := checkInputSize(data, options); err != nil {
+		return nil, err
+	}
+	data = prepareInput(data, options)
+
+	var values []json.RawMessage
+	err := parseEach(data, options, func(jsonBytes []byte, _, _ int) bool {
+		values = append(values, json.RawMessage(jsonBytes))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, noValidJSONError(data, options)
+	}
+	return values, nil
+}
+
+// UnmarshalString is like Unmarshal but takes the input as a string. It is a convenience
+// for callers holding a string: the input is copied to a byte slice as with []byte(s)
+func UnmarshalString(s string, v interface{}, opts ...Option) error {
+	return Unmarshal([]byte(s), v, opts...)
+}
+
+// UnmarshalReader reads JSON-encoded data from r and stores the result in the value pointed to by v
+// Like Unmarshal, it extracts the longest valid JSON object or array, not the first one like
+// Decoder, so r is read to its end even after a complete value. Without WithLargeFileMode
+// the whole input is buffered and the options apply as for Unmarshal; with it, memory is
+// bounded by the configured window size and the longest value within a window is extracted
+func UnmarshalReader(r io.Reader, v interface{}, opts ...Option) error {
+	options := applyOptions(opts...)
+	if limit := newInputLimit(options); limit != nil {
+		r = &limitReader{reader: r, limit: limit}
+	}
+	if options.windowSize == 0 {
+		if options.tee != nil {
+			r = io.TeeReader(r, options.tee)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return Unmarshal(data, v, opts...)
+	}
+
+	r = wrapReader(r, options)
+
+	jsonBytes, err := parseLongestWindowed(r, options)
+	if err != nil {
+		return err
+	}
+
+	return decode(jsonBytes, v, options)
+}
+
+// checkInputSize rejects data longer than the WithMaxInputSize limit
+func checkInputSize(data []byte, opts options) error {
+	if opts.maxInputSize > 0 && len(data) > opts.maxInputSize {
+		return errInputTooLarge(opts.maxInputSize)
+	}
+	return nil
+}
+
+// errInputTooLarge reports input beyond the WithMaxInputSize limit
+func errInputTooLarge(limit int) error {
+	return newInvalidJSONError(position{offset: int64(limit)}, fmt.Sprintf("input exceeds maximum size of %d bytes", limit))
+}
+
+// errGarbagePrefix reports input in which no value starts within the WithMaxGarbagePrefix limit
+func errGarbagePrefix(limit int) error {
+	return newNoValidJSONError(position{offset: int64(limit)}, fmt.Sprintf("no JSON value starts within the first %d bytes", limit))
+}
+
+// inputLimit is the number of bytes that may still be read from the input,
+// shared by all readers of a Decoder
+type inputLimit struct {
+	limit     int
+	remaining int
+}
+
+// newInputLimit returns the input limit of the options, or nil if there is none
+func newInputLimit(opts options) *inputLimit {
+	if opts.maxInputSize == 0 {
+		return nil
+	}
+	return &inputLimit{limit: opts.maxInputSize, remaining: opts.maxInputSize}
+}
+
+// limitReader fails once more bytes than allowed by limit have been read
+type limitReader struct {
+	reader io.Reader
+	limit  *inputLimit
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	if r.limit.remaining < 0 {
+		return 0, errInputTooLarge(r.limit.limit)
+	}
+
+	// Read one byte more than allowed to tell input of exactly the limit from longer input
+	if len(p) > r.limit.remaining+1 {
+		p = p[:r.limit.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.limit.remaining -= n
+	if r.limit.remaining < 0 {
+		return n + r.limit.remaining, errInputTooLarge(r.limit.limit)
+	}
+	return n, err
+}
+
+// prepareInput applies the options that rewrite the input before parsing
+func prepareInput(data []byte, opts options) []byte {
+	if opts.autoEncoding {
+		data = transcodeToUTF8(data)
+	}
+	if opts.csvQuoting {
+		data = unquoteCSV(data)
+	}
+	return data
+}
+
+// wrapReader applies the reader-level options to an input reader
+func wrapReader(r io.Reader, opts options) io.Reader {
+	if opts.tee != nil {
+		r = io.TeeReader(r, opts.tee)
+	}
+	if opts.autoEncoding {
+		r = newEncodingReader(r)
+	}
+	if opts.csvQuoting {
+		r = newCSVReader(r)
+	}
+	return r
+}
+
+// decode stores the extracted JSON into the value pointed to by v
+// It is shared by Unmarshal and Decoder so that both apply the same decoding options
+func decode(data []byte, v interface{}, opts options) error {
+	return wrapDecodeError(decodeValue(data, v, opts))
+}
+
+// wrapDecodeError wraps an error of encoding/json, or of a custom unmarshaler, into an
+// ErrInvalidJSON error that unwraps to it, so that all errors of the package are *Error
+func wrapDecodeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Type: ErrInvalidJSON, Message: err.Error(), err: err}
+}
+
+// decodeValue decodes data into v according to the options
+func decodeValue(data []byte, v interface{}, opts options) error {
+	if opts.strictArrayLen {
+		if err := checkArrayLengths(data, v); err != nil {
+			return err
+		}
+	}
+
+	if opts.materializes() {
+		value, err := materialize(data, opts)
+		if err != nil {
+			return err
+		}
+		return assignValue(v, value)
+	}
+
+	convert := numberConverter(opts)
+	if convert == nil && !opts.disallowUnknown {
+		return json.Unmarshal(data, v)
+	}
+
+	// json.Decoder stops after the first value, so data that json.Unmarshal rejects, such as
+	// a value followed by another one on the fast path, gets its error without touching v
+	if !json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if opts.disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if convert == nil {
+		return dec.Decode(v)
+	}
+
+	// Keep number tokens so that they can be converted according to the options
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	return resolveNumbers(reflect.ValueOf(v), convert)
+}
+
+// decodeSource stores the extracted JSON into the value pointed to by v, except that
+// values holding raw messages get the exact source bytes rather than the re-emitted ones
+func decodeSource(source, data []byte, v interface{}, opts options) error {
+	// The source is not valid JSON when the parser rewrote the value, as WithWindowsPathStrings does
+	if !opts.noStdlib && holdsRawMessage(reflect.TypeOf(v)) && json.Valid(source) {
+		return decode(source, v, opts)
+	}
+	return decode(data, v, opts)
+}
+
+var rawMessageType = reflect.TypeFor[json.RawMessage]()
+
+// rawMessageTypes caches holdsRawMessage by type
+var rawMessageTypes sync.Map
+
+// holdsRawMessage checks if values of type t may hold a json.RawMessage, in a map,
+// slice, array, pointer or struct field, embedded fields included
+func holdsRawMessage(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if holds, ok := rawMessageTypes.Load(t); ok {
+		return holds.(bool)
+	}
+	holds := findRawMessage(t, make(map[reflect.Type]bool))
+	rawMessageTypes.Store(t, holds)
+	return holds
+}
+
+// findRawMessage walks t for holdsRawMessage, where seen guards against recursive types
+func findRawMessage(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == rawMessageType {
+		return true
+	}
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return findRawMessage(t.Elem(), seen)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if field := t.Field(i); (field.IsExported() || field.Anonymous) && findRawMessage(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
 }
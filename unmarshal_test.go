@@ -1,7 +1,14 @@
 package jsonex
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestUnmarshal_BasicObject(t *testing.T) {
@@ -107,6 +114,40 @@ func TestUnmarshal_WithOptions(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_MaxDepthOnCleanInput(t *testing.T) {
+	// Clean input is what encoding/json would accept directly, so every entry point
+	// must leave it to the package parser when the depth limit is not the default
+	data := []byte(`{"a":{"b":{"c":{"d":{"e":{"f":{"g":{"h":{"i":{"j":"value"}}}}}}}}}`)
+	if applyOptions(WithMaxDepth(5)).allowsFastPath() {
+		t.Fatal("allowsFastPath() = true with a custom depth limit")
+	}
+
+	isDepthErr := func(err error) bool {
+		var jsonErr *Error
+		return errors.As(err, &jsonErr) && jsonErr.Message == "maximum nesting depth exceeded"
+	}
+
+	var result interface{}
+	if err := Unmarshal(data, &result, WithMaxDepth(5)); !isDepthErr(err) {
+		t.Errorf("Unmarshal = %v, expected depth error", err)
+	}
+	if _, _, err := UnmarshalAt(data, &result, WithMaxDepth(5)); !isDepthErr(err) {
+		t.Errorf("UnmarshalAt = %v, expected depth error", err)
+	}
+	if err := UnmarshalReader(strings.NewReader(string(data)), &result, WithMaxDepth(5)); !isDepthErr(err) {
+		t.Errorf("UnmarshalReader = %v, expected depth error", err)
+	}
+	if err := UnmarshalReader(strings.NewReader(string(data)), &result, WithMaxDepth(5), WithLargeFileMode(1024)); !isDepthErr(err) {
+		t.Errorf("UnmarshalReader in large file mode = %v, expected depth error", err)
+	}
+	if _, err := Extract(data, WithMaxDepth(5)); !isDepthErr(err) {
+		t.Errorf("Extract = %v, expected depth error", err)
+	}
+	if err := New(strings.NewReader(string(data)), WithMaxDepth(5)).Decode(&result); !isDepthErr(err) {
+		t.Errorf("Decode = %v, expected depth error", err)
+	}
+}
+
 func TestUnmarshal_ComplexJSON(t *testing.T) {
 	data := []byte(`prefix {
 		"users": [
@@ -143,4 +184,949 @@ func TestUnmarshal_ComplexJSON(t *testing.T) {
 	if settings["theme"] != "dark" {
 		t.Errorf("Expected theme=dark, got %v", settings["theme"])
 	}
-}
\ No newline at end of file
+}
+
+func TestUnmarshalReader(t *testing.T) {
+	data := `garbage {"short": 1} noise {"longer": {"nested": true}} end`
+
+	var result map[string]interface{}
+	if err := UnmarshalReader(strings.NewReader(data), &result); err != nil {
+		t.Fatalf("UnmarshalReader failed: %v", err)
+	}
+	if _, ok := result["longer"]; !ok {
+		t.Errorf("Expected longest JSON, got %v", result)
+	}
+}
+
+func TestUnmarshalReader_LongestNotFirst(t *testing.T) {
+	// The first value is complete long before the longer one arrives
+	data := `{"first": 1} ` + strings.Repeat("log line ", 100) + `{"second": [1, 2, 3]}`
+
+	var result map[string]interface{}
+	if err := UnmarshalReader(iotest.OneByteReader(strings.NewReader(data)), &result); err != nil {
+		t.Fatalf("UnmarshalReader failed: %v", err)
+	}
+	if _, ok := result["second"]; !ok {
+		t.Errorf("UnmarshalReader = %v, expected the longest value", result)
+	}
+
+	// The selection applies as for Unmarshal
+	result = nil
+	if err := UnmarshalReader(strings.NewReader(data), &result, WithFirstMatch()); err != nil || result["first"] != 1.0 {
+		t.Errorf("UnmarshalReader with WithFirstMatch = %v, %v", result, err)
+	}
+}
+
+func TestUnmarshalReader_LargeFileMode(t *testing.T) {
+	// The input is much larger than the window; the longest value straddles
+	// a window boundary but fits within half a window
+	longest := `{"longest": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]}`
+	data := strings.Repeat("x", 1000) + `{"a": 1}` +
+		strings.Repeat("y", 1050) + longest +
+		strings.Repeat("z", 1000) + `[1, 2]`
+
+	var result map[string]interface{}
+	err := UnmarshalReader(strings.NewReader(data), &result, WithLargeFileMode(128))
+	if err != nil {
+		t.Fatalf("UnmarshalReader failed: %v", err)
+	}
+	if _, ok := result["longest"]; !ok {
+		t.Errorf("Expected longest JSON, got %v", result)
+	}
+
+	// Values longer than the window can never be found
+	var tooLong map[string]interface{}
+	err = UnmarshalReader(strings.NewReader(longest), &tooLong, WithLargeFileMode(16))
+	if err == nil {
+		t.Errorf("Expected error for value longer than the window, got %v", tooLong)
+	}
+}
+
+func TestUnmarshalReader_LargeFileModeNoJSON(t *testing.T) {
+	var result interface{}
+	err := UnmarshalReader(strings.NewReader(strings.Repeat("text ", 100)), &result, WithLargeFileMode(64))
+	if err == nil {
+		t.Error("Expected error for input with no valid JSON")
+	}
+}
+
+func TestUnmarshal_RawMessageMap(t *testing.T) {
+	source := `{"a": {"x" : 1}, "b": "esc\"apedé\/", "c": [1, 2], "d": null}`
+	expected := map[string]string{
+		"a": `{"x" : 1}`,
+		"b": `"esc\"apedé\/"`,
+		"c": `[1, 2]`,
+		"d": `null`,
+	}
+
+	check := func(t *testing.T, result map[string]json.RawMessage) {
+		t.Helper()
+		if len(result) != len(expected) {
+			t.Fatalf("Expected %d fields, got %v", len(expected), result)
+		}
+		for key, raw := range expected {
+			if string(result[key]) != raw {
+				t.Errorf("Field %q = %s, expected %s", key, result[key], raw)
+			}
+		}
+	}
+
+	t.Run("Unmarshal clean input", func(t *testing.T) {
+		var result map[string]json.RawMessage
+		if err := Unmarshal([]byte(source), &result); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		check(t, result)
+	})
+
+	t.Run("Unmarshal noisy input", func(t *testing.T) {
+		var result map[string]json.RawMessage
+		if err := Unmarshal([]byte("log: "+source+" tail"), &result); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		check(t, result)
+	})
+
+	t.Run("Decoder", func(t *testing.T) {
+		var result map[string]json.RawMessage
+		if err := New(strings.NewReader("log: " + source + " tail")).Decode(&result); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		check(t, result)
+	})
+}
+
+func TestUnmarshal_RawMessageStruct(t *testing.T) {
+	type Meta struct {
+		Extra json.RawMessage `json:"extra"`
+	}
+	type Event struct {
+		Meta
+		Raw    json.RawMessage   `json:"raw"`
+		Amount json.Number       `json:"amount"`
+		Items  []json.RawMessage `json:"items"`
+	}
+
+	t.Run("Compact", func(t *testing.T) {
+		var result Event
+		if err := Unmarshal([]byte(`{"raw":{"x":1}}`), &result, WithMaxDepth(100)); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if string(result.Raw) != `{"x":1}` {
+			t.Errorf("Raw = %s, expected {\"x\":1}", result.Raw)
+		}
+	})
+
+	source := `log: {"raw": { "x" : 1 }, "amount": 1.50, "items": [ "a\/b", {} ], "extra": [1, 2]} tail`
+	decoders := map[string]func(v interface{}) error{
+		"Unmarshal": func(v interface{}) error { return Unmarshal([]byte(source), v) },
+		"Decoder":   func(v interface{}) error { return New(strings.NewReader(source)).Decode(v) },
+	}
+	for name, decode := range decoders {
+		t.Run(name, func(t *testing.T) {
+			var result *Event
+			if err := decode(&result); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if string(result.Raw) != `{ "x" : 1 }` {
+				t.Errorf("Raw = %s, expected the source bytes", result.Raw)
+			}
+			if result.Amount != "1.50" {
+				t.Errorf("Amount = %s, expected 1.50", result.Amount)
+			}
+			if len(result.Items) != 2 || string(result.Items[0]) != `"a\/b"` || string(result.Items[1]) != `{}` {
+				t.Errorf("Items = %s, expected the source bytes", result.Items)
+			}
+			if string(result.Extra) != `[1, 2]` {
+				t.Errorf("Extra = %s, expected [1, 2]", result.Extra)
+			}
+		})
+	}
+
+	// Types without raw messages, recursive ones included, are decoded from the extracted value
+	type Node struct {
+		Next  *Node `json:"next"`
+		Value int   `json:"value"`
+	}
+	if holdsRawMessage(reflect.TypeFor[*Node]()) || holdsRawMessage(reflect.TypeFor[*map[string]interface{}]()) {
+		t.Error("holdsRawMessage = true for a type without json.RawMessage")
+	}
+	if !holdsRawMessage(reflect.TypeFor[*Event]()) {
+		t.Error("holdsRawMessage(*Event) = false, expected true")
+	}
+}
+
+func TestUnmarshal_WithSelection(t *testing.T) {
+	data := []byte(`a {"id": 1} b {"id": 2, "nested": {"id": 9, "long": "value"}} c [3] {"id": 3} end`)
+
+	tests := []struct {
+		selection Selection
+		expected  float64
+	}{
+		{Longest, 2},
+		{First, 1},
+		{Last, 3},
+	}
+
+	for _, test := range tests {
+		var result map[string]interface{}
+		if err := Unmarshal(data, &result, WithSelection(test.selection)); err != nil {
+			t.Fatalf("Unmarshal with selection %d failed: %v", test.selection, err)
+		}
+		if result["id"] != test.expected {
+			t.Errorf("Selection %d selected %v, expected id=%v", test.selection, result, test.expected)
+		}
+	}
+}
+
+func TestUnmarshal_WithSelectionLast(t *testing.T) {
+	// Nested values overlap the enclosing one and are not candidates on their own
+	data := []byte(`{"first": 1} {"second": {"inner": [1]}} {"third": 3} {"broken": `)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithSelection(Last)); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["third"] != float64(3) {
+		t.Errorf("Expected third object, got %v", result)
+	}
+}
+
+func TestUnmarshal_WithSelectionPathologicalInput(t *testing.T) {
+	// Unclosed brackets between the values used to be parsed again from each of them
+	data := []byte(`{"a": 1} ` + strings.Repeat("[", 1<<20) + ` {"b": 2}`)
+
+	tests := []struct {
+		selection Selection
+		key       string
+	}{
+		{First, "a"},
+		{Last, "b"},
+	}
+
+	for _, test := range tests {
+		var result map[string]interface{}
+		if err := Unmarshal(data, &result, WithSelection(test.selection)); err != nil {
+			t.Fatalf("Unmarshal with selection %d failed: %v", test.selection, err)
+		}
+		if _, ok := result[test.key]; !ok || len(result) != 1 {
+			t.Errorf("Selection %d selected %v, expected key %s", test.selection, result, test.key)
+		}
+	}
+}
+
+func TestUnmarshal_WithFirstMatch(t *testing.T) {
+	data := []byte(`level=info msg={"user": "alice"} dump=[` + strings.Repeat(`{"noise": true}, `, 100) + `{}]`)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithFirstMatch()); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if result["user"] != "alice" {
+		t.Errorf("Unmarshal with WithFirstMatch = %v, expected the first object", result)
+	}
+
+	// The same input selects the large array by default
+	var longest interface{}
+	if err := Unmarshal(data, &longest); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, ok := longest.([]interface{}); !ok {
+		t.Errorf("Unmarshal = %T, expected the longest array", longest)
+	}
+}
+
+func TestUnmarshalReader_WithTee(t *testing.T) {
+	input := `noise {"a": 1} noise`
+
+	var tee strings.Builder
+	var result map[string]interface{}
+	if err := UnmarshalReader(strings.NewReader(input), &result, WithTee(&tee)); err != nil {
+		t.Fatalf("UnmarshalReader failed: %v", err)
+	}
+	if tee.String() != input {
+		t.Errorf("Teed %q, expected %q", tee.String(), input)
+	}
+}
+
+// rawCapture records the bytes passed to its UnmarshalJSON
+type rawCapture struct {
+	raw []byte
+}
+
+func (r *rawCapture) UnmarshalJSON(data []byte) error {
+	r.raw = append([]byte(nil), data...)
+	return nil
+}
+
+func TestUnmarshal_CustomUnmarshaler(t *testing.T) {
+	const message = "quote\" backslash\\ newline\n unicode é 😀"
+	source := `{"msg": "quote\" backslash\\ newline\n unicode é 😀"}`
+
+	check := func(t *testing.T, raw []byte) {
+		t.Helper()
+		if !json.Valid(raw) {
+			t.Fatalf("UnmarshalJSON received invalid JSON: %s", raw)
+		}
+		var decoded map[string]string
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Fatalf("Re-decoding %s failed: %v", raw, err)
+		}
+		if decoded["msg"] != message {
+			t.Errorf("Escapes were altered: got %q, expected %q", decoded["msg"], message)
+		}
+	}
+
+	for _, data := range []string{source, "log: " + source + " tail"} {
+		var top rawCapture
+		if err := Unmarshal([]byte(data), &top); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", data, err)
+		}
+		check(t, top.raw)
+
+		var decoded rawCapture
+		if err := New(strings.NewReader(data)).Decode(&decoded); err != nil {
+			t.Fatalf("Decode(%s) failed: %v", data, err)
+		}
+		check(t, decoded.raw)
+
+		var field struct {
+			Payload rawCapture `json:"payload"`
+		}
+		wrapped := `prefix {"payload": ` + source + `}`
+		if err := Unmarshal([]byte(wrapped), &field); err != nil {
+			t.Fatalf("Unmarshal(%s) failed: %v", wrapped, err)
+		}
+		check(t, field.Payload.raw)
+	}
+}
+
+func TestUnmarshalString(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts []Option
+	}{
+		{"Clean object", `{"name": "test", "value": 42}`, nil},
+		{"Surrounding whitespace", "\n  [1, 2, {\"a\": null}]\t\n", nil},
+		{"Garbage prefix", `log: {"a": 1}`, nil},
+		{"Several values", `{"a": 1} {"longer": [1, 2, 3]}`, nil},
+		{"Several values first", `{"a": 1} {"longer": [1, 2, 3]}`, []Option{WithSelection(First)}},
+		{"Broken first value", `{"a": {"b": 1} {"c": 2}`, nil},
+		{"Scalar", `"just a string"`, nil},
+		{"Lenient literals", `{"a": TRUE}`, []Option{WithLenientLiterals(true)}},
+		{"CSV quoting", `{""a"": ""b""}`, []Option{WithCSVQuoting(true)}},
+		{"Invalid UTF-8", "{\"a\": \"\xff\"}", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expected, result interface{}
+			expectedErr := Unmarshal([]byte(test.data), &expected, test.opts...)
+			err := UnmarshalString(test.data, &result, test.opts...)
+			if (err != nil) != (expectedErr != nil) {
+				t.Fatalf("UnmarshalString error = %v, Unmarshal error = %v", err, expectedErr)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("UnmarshalString = %#v, Unmarshal = %#v", result, expected)
+			}
+		})
+	}
+}
+
+func TestUnmarshalFrom(t *testing.T) {
+	data := []byte(`log 1 {"id": 1} log 2 [2, {"nested": true}] broken {"id": log 3 {"id": 3} tail`)
+
+	var results []interface{}
+	offsets := []int{0}
+	for offset := 0; ; {
+		var v interface{}
+		next, err := UnmarshalFrom(data, offset, &v)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("UnmarshalFrom(%d) failed: %v", offset, err)
+		}
+		if next <= offset {
+			t.Fatalf("UnmarshalFrom(%d) returned next %d, expected progress", offset, next)
+		}
+		results = append(results, v)
+		offsets = append(offsets, next)
+		offset = next
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{"id": float64(1)},
+		[]interface{}{float64(2), map[string]interface{}{"nested": true}},
+		map[string]interface{}{"id": float64(3)},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("UnmarshalFrom results = %v, expected %v", results, expected)
+	}
+
+	// Resuming from a saved offset continues with the following value
+	var resumed interface{}
+	if _, err := UnmarshalFrom(data, offsets[1], &resumed); err != nil {
+		t.Fatalf("UnmarshalFrom(%d) failed: %v", offsets[1], err)
+	}
+	if !reflect.DeepEqual(resumed, expected[1]) {
+		t.Errorf("Resumed = %v, expected %v", resumed, expected[1])
+	}
+	if string(data[offsets[1]-1]) != "}" {
+		t.Errorf("Offset %d is not just past a value", offsets[1])
+	}
+
+	var v interface{}
+	if _, err := UnmarshalFrom(data, len(data)+1, &v); err == nil {
+		t.Error("Expected error for offset out of range")
+	}
+}
+
+func TestUnmarshalFrom_PathologicalInput(t *testing.T) {
+	// Unclosed brackets and objects used to be parsed again from each of their start bytes
+	data := []byte(`{"id": 1} ` + strings.Repeat("[", 1<<20) + ` {"id": 2} ` + strings.Repeat(`{"a":`, 1<<18))
+
+	var ids []interface{}
+	offset := 0
+	for {
+		var v map[string]interface{}
+		next, err := UnmarshalFrom(data, offset, &v)
+		if err != nil {
+			break
+		}
+		ids = append(ids, v["id"])
+		offset = next
+	}
+
+	expected := []interface{}{float64(1), float64(2)}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("UnmarshalFrom ids = %v, expected %v", ids, expected)
+	}
+}
+
+func TestUnmarshal_LeadingBOM(t *testing.T) {
+	bom := "\xEF\xBB\xBF"
+	inputs := []string{
+		`{"a": 1, "b": [true, null]}`,
+		`[1, "two"]`,
+	}
+
+	for _, input := range inputs {
+		var expected interface{}
+		if err := json.Unmarshal([]byte(input), &expected); err != nil {
+			t.Fatalf("json.Unmarshal(%s) failed: %v", input, err)
+		}
+
+		// The default options allow the fast path, custom limits force the robust path
+		paths := map[string][]Option{
+			"fast":   nil,
+			"robust": {WithMaxDepth(100)},
+		}
+		for name, opts := range paths {
+			var result interface{}
+			if err := Unmarshal([]byte(bom+input), &result, opts...); err != nil {
+				t.Fatalf("Unmarshal(%s) on %s path failed: %v", input, name, err)
+			}
+			if !reflect.DeepEqual(result, expected) {
+				t.Errorf("Unmarshal(%s) on %s path = %v, expected %v", input, name, result, expected)
+			}
+		}
+
+		var decoded interface{}
+		if err := New(strings.NewReader(bom + input)).Decode(&decoded); err != nil {
+			t.Fatalf("Decode(%s) failed: %v", input, err)
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Errorf("Decode(%s) = %v, expected %v", input, decoded, expected)
+		}
+
+		// The mark may be split between reads
+		decoded = nil
+		if err := New(iotest.OneByteReader(strings.NewReader(bom + input))).Decode(&decoded); err != nil {
+			t.Fatalf("Decode(%s) one byte at a time failed: %v", input, err)
+		}
+
+		// Strict validation does not skip garbage, but it skips the mark
+		if err := ValidateFragment([]byte(bom + input)); err != nil {
+			t.Errorf("ValidateFragment(%s) failed: %v", input, err)
+		}
+		if err := New(strings.NewReader(bom + input)).Validate(); err != nil {
+			t.Errorf("Validate(%s) failed: %v", input, err)
+		}
+	}
+
+	// The fast path engages with the mark, so it allocates no more than without it
+	data := []byte(`{"a": 1}`)
+	withBOM := []byte(bom + `{"a": 1}`)
+	var result interface{}
+	plain := testing.AllocsPerRun(100, func() { _ = Unmarshal(data, &result) })
+	marked := testing.AllocsPerRun(100, func() { _ = Unmarshal(withBOM, &result) })
+	if marked > plain {
+		t.Errorf("Unmarshal with BOM made %v allocations, %v without, expected the fast path", marked, plain)
+	}
+
+	// Offsets count the bytes of the mark
+	err := ValidateFragment([]byte(bom + `{"k" 1}`))
+	if jsonErr, ok := err.(*Error); !ok || jsonErr.Position.Offset != 8 {
+		t.Errorf("ValidateFragment error = %v, expected offset 8", err)
+	}
+	if err := New(strings.NewReader(bom)).Decode(&result); err != io.EOF {
+		t.Errorf("Decode of only a BOM = %v, expected io.EOF", err)
+	}
+}
+
+func TestUnmarshal_WithAllowScalars(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"noise 42 noise", float64(42)},
+		{"42", float64(42)},
+		{"temperature: -3.5e2 degrees", float64(-350)},
+		{`log: "hello" done`, "hello"},
+		{"ok=true", true},
+		{"value: null", nil},
+		{`result: {"a": 1} and 12345`, map[string]interface{}{"a": float64(1)}},
+		{`item "a longer string wins" 7`, "a longer string wins"},
+	}
+
+	for _, test := range tests {
+		var result interface{}
+		if err := Unmarshal([]byte(test.input), &result, WithAllowScalars(true)); err != nil {
+			t.Errorf("Unmarshal(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Unmarshal(%q) = %#v, expected %#v", test.input, result, test.expected)
+		}
+	}
+
+	var n float64
+	if err := Unmarshal([]byte("noise 42 noise"), &n, WithAllowScalars(true)); err != nil || n != 42 {
+		t.Errorf("Unmarshal into float64 = %v, %v, expected 42", n, err)
+	}
+
+	// Scalars stay ignored by default
+	var result interface{}
+	if err := Unmarshal([]byte("noise 42 noise"), &result); err == nil {
+		t.Errorf("Unmarshal without WithAllowScalars = %v, expected error", result)
+	}
+
+	// A lone sign is not a number
+	if err := Unmarshal([]byte("a - b"), &result, WithAllowScalars(true)); err == nil {
+		t.Errorf("Unmarshal(%q) = %v, expected error", "a - b", result)
+	}
+
+	// The decoder extracts scalars from the stream as well
+	decoder := New(strings.NewReader(`id: 7, name: "x", ok: false`), WithAllowScalars(true))
+	for _, expected := range []interface{}{float64(7), "x", false} {
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if value != expected {
+			t.Errorf("Decode = %#v, expected %#v", value, expected)
+		}
+	}
+}
+
+func TestUnmarshal_EscapesDecodedOnce(t *testing.T) {
+	// Each escaped backslash decodes to exactly one backslash and never forms a new escape
+	inputs := []string{
+		`{"path":"C:\\temp\\new"}`,
+		`noise {"path":"C:\\temp\\new"} noise`,
+	}
+	expected := `C:\temp\new`
+
+	for _, input := range inputs {
+		for _, opts := range [][]Option{nil, {WithNoStdlib(true)}} {
+			var result map[string]interface{}
+			if err := Unmarshal([]byte(input), &result, opts...); err != nil {
+				t.Fatalf("Unmarshal(%s) failed: %v", input, err)
+			}
+			if result["path"] != expected {
+				t.Errorf("Unmarshal(%s) = %q, expected %q", input, result["path"], expected)
+			}
+		}
+	}
+}
+
+func TestUnmarshal_WithDisallowUnknownFields(t *testing.T) {
+	var result struct {
+		Name string `json:"name"`
+	}
+
+	inputs := []string{
+		`{"name":"x","extra":1}`,
+		`noise {"name":"x","extra":1} noise`,
+	}
+	for _, input := range inputs {
+		if err := Unmarshal([]byte(input), &result, WithDisallowUnknownFields()); err == nil {
+			t.Errorf("Unmarshal(%s) = %v, expected unknown field error", input, result)
+		}
+		if err := Unmarshal([]byte(input), &result); err != nil || result.Name != "x" {
+			t.Errorf("Unmarshal(%s) without the option = %v, %v", input, result, err)
+		}
+	}
+
+	// The check composes with number options
+	if err := Unmarshal([]byte(`{"name":"x","n":1}`), &result, WithDisallowUnknownFields(), WithIntegerNumbers(true)); err == nil {
+		t.Error("Unmarshal with integer numbers accepted an unknown field")
+	}
+
+	// A clean leading value does not hide the longer value after it
+	if err := Unmarshal([]byte(`{"name":"x"} {"name":"longer","extra":1}`), &result, WithDisallowUnknownFields()); err == nil {
+		t.Errorf("Unmarshal = %v, expected unknown field error from the longest value", result)
+	}
+}
+
+func TestUnmarshal_WithUseNumber(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []Option
+	}{
+		{"Fast path", `{"amount": 1234567890123456789}`, nil},
+		{"Robust path", `total: {"amount": 1234567890123456789} EOF`, nil},
+		{"With max depth", `{"amount": 1234567890123456789}`, []Option{WithMaxDepth(5)}},
+		{"Without stdlib", `{"amount": 1234567890123456789}`, []Option{WithNoStdlib(true)}},
+		{"Clean value followed by a longer one", `{"amount": 1} {"amount": 1234567890123456789}`, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var result map[string]interface{}
+			if err := Unmarshal([]byte(test.input), &result, append(test.opts, WithUseNumber())...); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			n, ok := result["amount"].(json.Number)
+			if !ok || n.String() != "1234567890123456789" {
+				t.Errorf("amount = %#v, expected json.Number 1234567890123456789", result["amount"])
+			}
+		})
+	}
+}
+
+func TestUnmarshalAll(t *testing.T) {
+	data := []byte(`log {"a": 1} junk [1, {"b": 2}] {"broken": } more {"c": [3]} end`)
+
+	values, err := UnmarshalAll(data)
+	if err != nil {
+		t.Fatalf("UnmarshalAll failed: %v", err)
+	}
+
+	expected := []string{`{"a": 1}`, `[1, {"b": 2}]`, `{"c": [3]}`}
+	if len(values) != len(expected) {
+		t.Fatalf("UnmarshalAll returned %d values %q, expected %d", len(values), values, len(expected))
+	}
+	for i, value := range values {
+		var got, want interface{}
+		if err := json.Unmarshal(value, &got); err != nil {
+			t.Fatalf("value %d %q is not valid JSON: %v", i, value, err)
+		}
+		if err := json.Unmarshal([]byte(expected[i]), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("value %d = %s, expected %s", i, value, expected[i])
+		}
+	}
+
+	if _, err := UnmarshalAll([]byte("no json here")); err == nil {
+		t.Error("UnmarshalAll without JSON succeeded, expected error")
+	}
+	if _, err := UnmarshalAll(nil); err == nil {
+		t.Error("UnmarshalAll with empty input succeeded, expected error")
+	}
+
+	// Values from CSV exports are unquoted first
+	values, err = UnmarshalAll([]byte(`1,"{""a"":1}","[""x""]"`), WithCSVQuoting(true))
+	if err != nil || len(values) != 2 {
+		t.Errorf("UnmarshalAll with CSV quoting = %q, %v, expected 2 values", values, err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     []Option
+		expected string
+	}{
+		{"Clean input", `{"a": 1}`, nil, `{"a": 1}`},
+		{"Longest value", "log [1] then {\"a\": {\"b\": [1, 2]}}\n trailing", nil, `{"a": {"b": [1, 2]}}`},
+		{"First value", `log [1] then {"a": {"b": [1, 2]}}`, []Option{WithFirstMatch()}, `[1]`},
+		{"CSV quoting", `1,"{""a"":1}"`, []Option{WithCSVQuoting(true)}, `{"a":1}`},
+		{"Windows paths", `path {"p": "C:\Users"}`, []Option{WithWindowsPathStrings(true)}, `{"p":"C:\\Users"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := []byte(test.input)
+			result, err := Extract(data, test.opts...)
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if string(result) != test.expected {
+				t.Errorf("Extract = %s, expected %s", result, test.expected)
+			}
+			if !json.Valid(result) {
+				t.Errorf("Extract = %s, which is not valid JSON", result)
+			}
+
+			// The result does not alias the input
+			for i := range data {
+				data[i] = 'x'
+			}
+			if string(result) != test.expected {
+				t.Errorf("Extract result changed with the input to %s", result)
+			}
+		})
+	}
+
+	if _, err := Extract([]byte("no json")); err == nil {
+		t.Error("Extract without JSON succeeded, expected error")
+	}
+	if _, err := Extract([]byte(`{"a": 01}`)); err == nil {
+		t.Error("Extract of an invalid number succeeded, expected error")
+	}
+	if _, err := Extract(nil); err == nil {
+		t.Error("Extract of empty input succeeded, expected error")
+	}
+}
+
+func TestUnmarshalAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  []Option
+		value string
+	}{
+		{"Fast path", `{"a": [1, 2]}`, nil, `{"a": [1, 2]}`},
+		{"Fast path with BOM", "\xEF\xBB\xBF[1, 2]", nil, `[1, 2]`},
+		{"Surrounding whitespace", "\n  {\"a\": 1}\t\n", nil, `{"a": 1}`},
+		{"Garbage", `prefix {"x": {"y": 1}} suffix {"z": 2}`, nil, `{"x": {"y": 1}}`},
+		{"First selection", `a [1] b [2, 3, 4]`, []Option{WithFirstMatch()}, `[1]`},
+		{"Robust path", `log: [1, {"b": true}] end`, []Option{WithMaxDepth(10)}, `[1, {"b": true}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := []byte(test.input)
+			var result interface{}
+			start, end, err := UnmarshalAt(data, &result, test.opts...)
+			if err != nil {
+				t.Fatalf("UnmarshalAt failed: %v", err)
+			}
+			if got := string(data[start:end]); got != test.value {
+				t.Errorf("data[%d:%d] = %q, expected %q", start, end, got, test.value)
+			}
+			if result == nil {
+				t.Error("UnmarshalAt did not decode the value")
+			}
+		})
+	}
+
+	var result interface{}
+	if _, _, err := UnmarshalAt([]byte("no json"), &result); err == nil {
+		t.Error("UnmarshalAt without JSON succeeded, expected error")
+	}
+	if _, _, err := UnmarshalAt([]byte(`"{""a"":1}"`), &result, WithCSVQuoting(true)); err == nil {
+		t.Error("UnmarshalAt with CSV quoting succeeded, expected error")
+	}
+}
+
+func TestUnmarshal_WithMaxInputSize(t *testing.T) {
+	data := []byte(`noise {"a": [1, 2, 3]} noise`)
+
+	var result map[string]interface{}
+	if err := Unmarshal(data, &result, WithMaxInputSize(len(data))); err != nil {
+		t.Errorf("Unmarshal at the limit failed: %v", err)
+	}
+
+	isSizeError := func(err error) bool {
+		jsonErr, ok := err.(*Error)
+		return ok && jsonErr.Type == ErrInvalidJSON && strings.Contains(jsonErr.Message, "maximum size")
+	}
+
+	opts := WithMaxInputSize(len(data) - 1)
+	if err := Unmarshal(data, &result, opts); !isSizeError(err) {
+		t.Errorf("Unmarshal beyond the limit = %v, expected size error", err)
+	}
+	if _, _, err := UnmarshalAt(data, &result, opts); !isSizeError(err) {
+		t.Errorf("UnmarshalAt beyond the limit = %v, expected size error", err)
+	}
+	if _, err := UnmarshalAll(data, opts); !isSizeError(err) {
+		t.Errorf("UnmarshalAll beyond the limit = %v, expected size error", err)
+	}
+	if err := UnmarshalReader(strings.NewReader(string(data)), &result, opts); !isSizeError(err) {
+		t.Errorf("UnmarshalReader beyond the limit = %v, expected size error", err)
+	}
+	if err := UnmarshalReader(strings.NewReader(string(data)), &result, opts, WithLargeFileMode(8)); !isSizeError(err) {
+		t.Errorf("UnmarshalReader in large file mode beyond the limit = %v, expected size error", err)
+	}
+}
+
+func TestUnmarshal_WithMaxGarbagePrefix(t *testing.T) {
+	opts := WithMaxGarbagePrefix(6)
+
+	var result interface{}
+	if err := Unmarshal([]byte(`noise {"a": 1} noise`), &result, opts); err != nil {
+		t.Errorf("Unmarshal within the limit failed: %v", err)
+	}
+
+	// A longer value starting beyond the limit is not extracted
+	if err := Unmarshal([]byte(`noise [1] then {"a": [1, 2, 3]}`), &result, opts); err != nil || !reflect.DeepEqual(result, []interface{}{float64(1)}) {
+		t.Errorf("Unmarshal = %v, %v, expected [1]", result, err)
+	}
+
+	isPrefixError := func(err error) bool {
+		jsonErr, ok := err.(*Error)
+		return ok && jsonErr.Type == ErrInvalidJSON && errors.Is(err, ErrNoValidJSON) && !errors.Is(err, ErrNoJSON)
+	}
+
+	data := []byte(`garbage {"a": 1}` + strings.Repeat(" noise", 1000))
+	if err := Unmarshal(data, &result, opts); !isPrefixError(err) {
+		t.Errorf("Unmarshal beyond the limit = %v, expected garbage prefix error", err)
+	}
+	if _, err := Extract(data, opts); !isPrefixError(err) {
+		t.Errorf("Extract beyond the limit = %v, expected garbage prefix error", err)
+	}
+	if err := Unmarshal(data, &result, opts, WithFirstMatch()); !isPrefixError(err) {
+		t.Errorf("Unmarshal of the first match beyond the limit = %v, expected garbage prefix error", err)
+	}
+	if err := UnmarshalReader(bytes.NewReader(data), &result, opts, WithLargeFileMode(4)); !isPrefixError(err) {
+		t.Errorf("UnmarshalReader in large file mode beyond the limit = %v, expected garbage prefix error", err)
+	}
+
+	// Only the first value is bounded
+	values, err := UnmarshalAll([]byte(`noise [1] then a long gap [2]`), opts)
+	if err != nil || len(values) != 2 {
+		t.Errorf("UnmarshalAll = %q, %v, expected two values", values, err)
+	}
+	if _, err := UnmarshalAll(data, opts); !isPrefixError(err) {
+		t.Errorf("UnmarshalAll beyond the limit = %v, expected garbage prefix error", err)
+	}
+
+	// A value in large file mode may start in a later window within the limit
+	if err := UnmarshalReader(strings.NewReader(`noise xyz[1]`), &result, WithMaxGarbagePrefix(10), WithLargeFileMode(8)); err != nil {
+		t.Errorf("UnmarshalReader in large file mode within the limit failed: %v", err)
+	}
+}
+
+func TestUnmarshal_WithStrictBoundaries(t *testing.T) {
+	var result map[string]int
+	if err := Unmarshal([]byte(" \n{\"a\": 1}\t\n"), &result, WithStrictBoundaries()); err != nil || result["a"] != 1 {
+		t.Errorf("Unmarshal with surrounding whitespace = %v, %v", result, err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		errType ErrorType
+		offset  int64
+		message string
+	}{
+		{"Leading junk", `log: {"a": 1}`, ErrSyntax, 0, "unexpected content before JSON value: 'l'"},
+		{"Trailing junk", "{\"a\": 1}\n done", ErrSyntax, 10, "unexpected content after JSON value: 'd'"},
+		{"Second value", `{"a": 1}{"b": 2}`, ErrSyntax, 8, "unexpected content after JSON value: '{'"},
+		{"Malformed value", `{"a": 1,}`, ErrSyntax, 8, "expected '\"', found '}'"},
+		{"Whitespace only", " \n ", ErrInvalidJSON, 3, "no valid JSON found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var v interface{}
+			err := Unmarshal([]byte(test.input), &v, WithStrictBoundaries())
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != test.errType || jsonErr.Position.Offset != test.offset || jsonErr.Message != test.message {
+				t.Errorf("Unmarshal(%q) = %v, expected %v at offset %d: %s", test.input, err, test.errType, test.offset, test.message)
+			}
+		})
+	}
+
+	// Comments allowed by WithAllowComments count as whitespace
+	var array []int
+	if err := Unmarshal([]byte("/* ids */ [1, 2] // end"), &array, WithStrictBoundaries(), WithAllowComments()); err != nil || len(array) != 2 {
+		t.Errorf("Unmarshal with comments = %v, %v", array, err)
+	}
+
+	start, end, err := UnmarshalAt([]byte(`  [1, 2]  `), &array, WithStrictBoundaries())
+	if err != nil || start != 2 || end != 8 {
+		t.Errorf("UnmarshalAt = %d, %d, %v, expected 2, 8", start, end, err)
+	}
+}
+
+func TestUnmarshal_WrapsDecodeError(t *testing.T) {
+	var result struct {
+		Count int `json:"count"`
+	}
+	err := Unmarshal([]byte(`log: {"count": "three"}`), &result)
+	var jsonErr *Error
+	if !errors.As(err, &jsonErr) || jsonErr.Type != ErrInvalidJSON {
+		t.Fatalf("Unmarshal = %v, expected ErrInvalidJSON", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Field != "count" {
+		t.Errorf("Unmarshal = %v, expected to unwrap to *json.UnmarshalTypeError", err)
+	}
+	if jsonErr.Message != typeErr.Error() {
+		t.Errorf("Message = %q, expected %q", jsonErr.Message, typeErr.Error())
+	}
+
+	// Decoder errors and destinations that are not pointers are wrapped as well
+	err = New(strings.NewReader(`{"count": true}`)).Decode(&result)
+	if !errors.As(err, &typeErr) || !errors.Is(err, ErrInvalidJSONSentinel) {
+		t.Errorf("Decode = %v, expected a wrapped *json.UnmarshalTypeError", err)
+	}
+	var invalidErr *json.InvalidUnmarshalError
+	if err := Unmarshal([]byte(`{}`), result); !errors.As(err, &invalidErr) {
+		t.Errorf("Unmarshal into a non-pointer = %v, expected a wrapped *json.InvalidUnmarshalError", err)
+	}
+
+	// Errors of the parser have nothing to unwrap
+	err = Unmarshal([]byte(`log: {"count": }`), &result)
+	if !errors.As(err, &jsonErr) || jsonErr.Unwrap() != nil {
+		t.Errorf("Unwrap of %v = %v, expected nil", err, jsonErr.Unwrap())
+	}
+}
+
+func TestUnmarshal_WithDelimiters(t *testing.T) {
+	delimiters := WithDelimiters("<<<JSON", "JSON>>>")
+
+	// A longer value outside the markers is ignored
+	input := `{"outside": [1, 2, 3, 4, 5]} <<<JSON {"a": 1} JSON>>> <<<JSON [2] JSON>>>`
+	var result interface{}
+	start, end, err := UnmarshalAt([]byte(input), &result, delimiters)
+	if err != nil || input[start:end] != `{"a": 1}` {
+		t.Errorf("UnmarshalAt = %q, %v, expected the first delimited value", input[start:end], err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		errType ErrorType
+		offset  int64
+		message string
+	}{
+		{"Invalid region", "log\n<<<JSON {\"a\": } JSON>>>", ErrSyntax, 18, "unexpected character '}'"},
+		{"Content after value", `<<<JSON {"a": 1} x JSON>>>`, ErrSyntax, 17, "unexpected content after JSON value: 'x'"},
+		{"Missing end", `<<<JSON {"a": 1}`, ErrEOF, 16, "end delimiter not found"},
+		{"Missing start", `{"a": 1}`, ErrInvalidJSON, 0, "start delimiter not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Unmarshal([]byte(test.input), &result, delimiters)
+			jsonErr, ok := err.(*Error)
+			if !ok || jsonErr.Type != test.errType || jsonErr.Position.Offset != test.offset || jsonErr.Message != test.message {
+				t.Errorf("Unmarshal(%q) = %v, expected %v at offset %d: %s", test.input, err, test.errType, test.offset, test.message)
+			}
+		})
+	}
+
+	if !errors.Is(Unmarshal([]byte(`{"a": 1}`), &result, delimiters), ErrNoJSON) {
+		t.Error("Unmarshal without a start delimiter does not match ErrNoJSON")
+	}
+}
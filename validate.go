@@ -0,0 +1,62 @@
+package jsonex
+
+import (
+	"io"
+)
+
+// Valid reports whether data contains a JSON value that Unmarshal would extract with the
+// same options. Like json.Valid it builds no Go value, but garbage around the value is
+// skipped as by Unmarshal
+func Valid(data []byte, opts ...Option) bool {
+	if len(data) == 0 {
+		return false
+	}
+	options := applyOptions(opts...)
+	if checkInputSize(data, options) != nil {
+		return false
+	}
+	data = prepareInput(data, options)
+
+	_, _, _, err := parseSelected(data, options)
+	return err == nil
+}
+
+// ValidateFragment strictly validates b as a single complete JSON value, such as a
+// stored raw message. Unlike Unmarshal, no garbage is skipped: anything other than
+// whitespace around the value is an error. Parsing options such as WithStrictUTF8 and
+// WithLenientLiterals apply. The returned error is an *Error with the position in b
+func ValidateFragment(b []byte, opts ...Option) error {
+	options := applyOptions(opts...)
+	if err := checkInputSize(b, options); err != nil {
+		return err
+	}
+	p := newParser(&bytesReader{data: b}, options)
+
+	if err := p.scanner.skipWhitespace(); err != nil {
+		if err == io.EOF {
+			return newEOFError(p.scanner.position(), "empty fragment")
+		}
+		return err
+	}
+	buf := p.getValueBuffer()
+	defer putBuffer(buf)
+
+	err := p.parseElement(buf)
+	if err == nil {
+		err = buf.err
+	}
+	if err == io.EOF {
+		return newEOFError(p.scanner.position(), "unexpected end of input in JSON value")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := p.scanner.skipWhitespace(); err != io.EOF {
+		if err != nil {
+			return err
+		}
+		return newSyntaxError(p.scanner.position(), "unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package jsonex
+
+import (
+	"testing"
+)
+
+func TestValidateFragment(t *testing.T) {
+	valid := []string{
+		`{"a": 1, "b": [true, false, null]}`,
+		`[]`,
+		`  {"nested": {"deep": [1, -2.5e3, "x"]}}  `,
+		`"string"`,
+		`-12.5e+3`,
+		`null`,
+		`{"unicode": "日本語 é 😀"}`,
+		"\n[1,\n 2]\n",
+	}
+	for _, fragment := range valid {
+		if err := ValidateFragment([]byte(fragment)); err != nil {
+			t.Errorf("ValidateFragment(%q) = %v, expected nil", fragment, err)
+		}
+	}
+
+	invalid := []struct {
+		fragment string
+		errType  ErrorType
+		offset   int64
+	}{
+		{``, ErrEOF, 0},
+		{`   `, ErrEOF, 3},
+		{`garbage {"a": 1}`, ErrSyntax, 0},
+		{`{"a": 1} trailing`, ErrSyntax, 9},
+		{`{"a": 1}{"b": 2}`, ErrSyntax, 8},
+		{`[1, 2,]`, ErrSyntax, 6},
+		{`{"a" 1}`, ErrSyntax, 5},
+		{`{"a": 1,}`, ErrSyntax, 8},
+		{`{"a": "unterminated`, ErrEOF, 19},
+		{`{"a": "\x"}`, ErrEscape, 8},
+		{"{\"a\": \"\xff\"}", ErrUnicode, 7},
+		{`{"a": tru}`, ErrSyntax, 9},
+		{`[1-2]`, ErrSyntax, 2},
+		{`{"a": 01}`, ErrSyntax, 7},
+	}
+	for _, test := range invalid {
+		err := ValidateFragment([]byte(test.fragment))
+		jsonErr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("ValidateFragment(%q) = %v, expected *Error", test.fragment, err)
+			continue
+		}
+		if jsonErr.Type != test.errType || jsonErr.Position.Offset != test.offset {
+			t.Errorf("ValidateFragment(%q) = %v, expected %v at offset %d", test.fragment, err, test.errType, test.offset)
+		}
+	}
+
+	// Parsing options apply to the fragment
+	if err := ValidateFragment([]byte(`{"a": TRUE}`), WithLenientLiterals(true)); err != nil {
+		t.Errorf("ValidateFragment with lenient literals = %v, expected nil", err)
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		input    string
+		opts     []Option
+		expected bool
+	}{
+		{`{"a": 1}`, nil, true},
+		{`log: {"a": [1, 2]} trailing`, nil, true},
+		{"\xEF\xBB\xBF[1]", nil, true},
+		{`no json here`, nil, false},
+		{``, nil, false},
+		{`{"a": 1`, nil, false},
+		{`{"a": 01}`, nil, false},
+		{`noise 42`, nil, false},
+		{`noise 42`, []Option{WithAllowScalars(true)}, true},
+		{`{"a": {"b": 1}}`, []Option{WithMaxDepth(1)}, false},
+		{`{"a": 1}`, []Option{WithMaxInputSize(4)}, false},
+		{`{"a": 1, "a": 2}`, []Option{WithDisallowDuplicateKeys()}, false},
+	}
+
+	for _, test := range tests {
+		if result := Valid([]byte(test.input), test.opts...); result != test.expected {
+			t.Errorf("Valid(%q) = %v, expected %v", test.input, result, test.expected)
+		}
+
+		// Valid agrees with Unmarshal
+		var v interface{}
+		if ok := Unmarshal([]byte(test.input), &v, test.opts...) == nil; ok != test.expected {
+			t.Errorf("Unmarshal(%q) succeeded = %v, expected %v", test.input, ok, test.expected)
+		}
+	}
+}